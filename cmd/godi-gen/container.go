@@ -0,0 +1,909 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// --------
+//
+// Container generation.  A function marked //godi:container declares a set
+// of bindings with ordinary RegistrationContext calls, so it can also be run
+// against a dynamic scope:
+//
+//	//godi:container AppContainer
+//	func appBindings(c godi.RegistrationContext) {
+//		c.RegisterTypeImplementor((*Animal)(nil), Hippo{}, true, nil)
+//		c.RegisterProvider((*Keeper)(nil), NewKeeper, false)
+//	}
+//
+// From it, godi-gen -container writes an AppContainer that constructs each
+// binding with plain Go code: one method per binding, calling the methods of
+// its dependencies.  Missing bindings and cycles are reported when
+// generating, and since the result is ordinary Go, type mismatches are
+// compile errors.
+//
+// The container embeds a dynamic scope, so it is a RegistrationContext
+// itself: Resolve serves the generated bindings without reflection, and
+// everything else falls through to the scope, as do optional dependencies
+// the spec doesn't bind.  The bindings are also registered in the scope, so
+// dynamic registrations can depend on them.  The generated methods don't
+// run hooks, decorators or instance initializers, and Start and Stop don't
+// manage their instances.
+//
+// --------
+
+const containerDirective = "//godi:container"
+
+type bindKind int
+
+const (
+	bindType bindKind = iota
+	bindProvider
+	bindInstance
+)
+
+// staticDep is a provider parameter or injected field of a binding.
+type staticDep struct {
+	t        types.Type
+	name     string
+	field    string
+	optional bool
+	pos      token.Pos
+
+	// binding is the binding that satisfies it, or nil if it is optional and
+	// unbound, in which case it is resolved from the container's scope.
+	binding *staticBinding
+}
+
+// staticBinding is one registration call in a container spec.
+type staticBinding struct {
+	kind   bindKind
+	target *types.Named
+	name   string
+	cached bool
+	pos    token.Pos
+
+	// result is the type the binding's method returns: the target for
+	// interfaces, and the implementor or provider's result for concrete
+	// targets, as godi would return.
+	result types.Type
+
+	impl       *types.Named
+	initialize bool
+	provider   *types.Func
+	returnsErr bool
+	instance   ast.Expr
+
+	deps   []*staticDep
+	method string
+}
+
+type container struct {
+	name     string
+	spec     string
+	bindings []*staticBinding
+}
+
+func (p *container) hasCached() bool {
+	for _, b := range p.bindings {
+		if b.cached {
+			return true
+		}
+	}
+	return false
+}
+
+type containerGen struct {
+	fset    *token.FileSet
+	pkg     *types.Package
+	info    *types.Info
+	imports map[[2]string]bool
+	errs    []error
+
+	// optional is set if any binding has an unbound optional dependency,
+	// which needs the godiOptional helper.
+	optional bool
+
+	// reserved are the RegistrationContext methods, which binding methods
+	// can't shadow.
+	reserved map[string]bool
+}
+
+// generateContainers returns the formatted source of the containers declared
+// in files.
+func generateContainers(fset *token.FileSet, pkg *types.Package, files []*ast.File, info *types.Info) ([]byte, error) {
+	g := &containerGen{fset: fset, pkg: pkg, info: info, imports: map[[2]string]bool{}}
+
+	var containers []*container
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			for _, c := range fn.Doc.List {
+				name, ok := strings.CutPrefix(c.Text, containerDirective)
+				if !ok || (name != "" && name[0] != ' ' && name[0] != '\t') {
+					continue
+				}
+				if ctr := g.parseSpec(fn, strings.TrimSpace(name), c.Pos()); ctr != nil {
+					containers = append(containers, ctr)
+				}
+			}
+		}
+	}
+	if len(containers) == 0 && len(g.errs) == 0 {
+		return nil, fmt.Errorf("No %s functions in package %s", containerDirective, pkg.Name())
+	}
+
+	for _, ctr := range containers {
+		g.check(ctr)
+	}
+	if len(g.errs) > 0 {
+		return nil, errors.Join(g.errs...)
+	}
+	return g.source(containers)
+}
+
+func (p *containerGen) errorf(pos token.Pos, format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf("%v: %s", p.fset.Position(pos), fmt.Sprintf(format, args...)))
+}
+
+// isGodi returns true if obj is the godi declaration with the given name.
+func isGodi(obj types.Object, name string) bool {
+	return obj != nil && obj.Pkg() != nil && obj.Pkg().Path() == godiPath && obj.Name() == name
+}
+
+// parseSpec reads the bindings declared by fn.
+func (p *containerGen) parseSpec(fn *ast.FuncDecl, name string, pos token.Pos) *container {
+	if !token.IsIdentifier(name) {
+		p.errorf(pos, "%s needs the name of the container to generate", containerDirective)
+		return nil
+	}
+
+	params := fn.Type.Params.List
+	if fn.Recv != nil || len(params) != 1 || len(params[0].Names) != 1 {
+		p.errorf(fn.Pos(), "%s must take a single godi.RegistrationContext", fn.Name.Name)
+		return nil
+	}
+	param := p.info.Defs[params[0].Names[0]]
+	named, ok := param.Type().(*types.Named)
+	if !ok || !isGodi(named.Obj(), "RegistrationContext") {
+		p.errorf(fn.Pos(), "%s must take a single godi.RegistrationContext", fn.Name.Name)
+		return nil
+	}
+	if p.reserved == nil {
+		p.reserved = map[string]bool{}
+		iface := named.Underlying().(*types.Interface)
+		for i := 0; i < iface.NumMethods(); i++ {
+			p.reserved[iface.Method(i).Name()] = true
+		}
+	}
+
+	ctr := &container{name: name, spec: fn.Name.Name}
+	for _, stmt := range fn.Body.List {
+		if b := p.parseStmt(stmt, param); b != nil {
+			ctr.bindings = append(ctr.bindings, b)
+		}
+	}
+	return ctr
+}
+
+// parseStmt reads a registration call on param.
+func (p *containerGen) parseStmt(stmt ast.Stmt, param types.Object) *staticBinding {
+	var call *ast.CallExpr
+	var method string
+	if es, ok := stmt.(*ast.ExprStmt); ok {
+		call, _ = es.X.(*ast.CallExpr)
+	}
+	if call != nil {
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && p.info.Uses[id] == param {
+				method = sel.Sel.Name
+			}
+		}
+	}
+	if method == "" {
+		p.errorf(stmt.Pos(), "Only calls to %s's Register methods are supported in a container", param.Name())
+		return nil
+	}
+
+	args := call.Args
+	b := &staticBinding{pos: call.Pos()}
+	var opts []ast.Expr
+	switch method {
+	case "RegisterTypeImplementor":
+		if len(args) < 4 {
+			return nil
+		}
+		b.kind, opts = bindType, args[4:]
+		if !p.parseImplementor(b, args[1]) {
+			return nil
+		}
+		if !p.info.Types[args[3]].IsNil() {
+			p.errorf(args[3].Pos(), "InitializeCallbacks aren't supported in a container, implement GodiInit instead")
+		}
+	case "RegisterProvider":
+		if len(args) < 3 {
+			return nil
+		}
+		b.kind, opts = bindProvider, args[3:]
+		if !p.parseProvider(b, args[1]) {
+			return nil
+		}
+	case "RegisterInstanceImplementor":
+		if len(args) < 2 {
+			return nil
+		}
+		b.kind, b.cached, opts = bindInstance, true, args[2:]
+		b.instance = args[1]
+		p.checkInstance(args[1])
+	default:
+		p.errorf(call.Pos(), "%s isn't supported in a container", method)
+		return nil
+	}
+
+	if b.kind != bindInstance {
+		tv := p.info.Types[args[2]]
+		if tv.Value == nil || tv.Value.Kind() != constant.Bool {
+			p.errorf(args[2].Pos(), "cached must be a constant")
+			return nil
+		}
+		b.cached = constant.BoolVal(tv.Value)
+	}
+
+	target := p.info.TypeOf(args[0])
+	if ptr, ok := target.(*types.Pointer); ok {
+		target = ptr.Elem()
+	}
+	if b.target, _ = target.(*types.Named); b.target == nil || b.target.TypeArgs() != nil {
+		p.errorf(args[0].Pos(), "Target %v must be a named, non-generic type", target)
+		return nil
+	}
+
+	for _, opt := range opts {
+		if !p.parseOption(b, opt) {
+			p.errorf(opt.Pos(), "Only godi.Named options are supported in a container")
+		}
+	}
+
+	if !p.setResult(b) {
+		return nil
+	}
+	return b
+}
+
+// parseOption reads a godi.Named("name") option.
+func (p *containerGen) parseOption(b *staticBinding, opt ast.Expr) bool {
+	call, ok := opt.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !isGodi(p.info.Uses[sel.Sel], "Named") {
+		return false
+	}
+	tv := p.info.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return false
+	}
+	b.name = constant.StringVal(tv.Value)
+	return true
+}
+
+// parseImplementor reads the implementor of a type binding, and its injected
+// fields.
+func (p *containerGen) parseImplementor(b *staticBinding, arg ast.Expr) bool {
+	t := p.info.TypeOf(arg)
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	impl, _ := t.(*types.Named)
+	var st *types.Struct
+	if impl != nil {
+		st, _ = impl.Underlying().(*types.Struct)
+	}
+	if st == nil || impl.TypeArgs() != nil {
+		p.errorf(arg.Pos(), "Implementor %v must be a named, non-generic struct", t)
+		return false
+	}
+	b.impl = impl
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("godi")
+		if !ok {
+			continue
+		}
+		if !f.Exported() && impl.Obj().Pkg() != p.pkg {
+			p.errorf(arg.Pos(), "Can't inject unexported field %v.%s from another package", impl, f.Name())
+			continue
+		}
+
+		dep := &staticDep{t: f.Type(), field: f.Name(), pos: f.Pos()}
+		valid := true
+		for _, opt := range strings.Split(tag, ",") {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case opt == "":
+			case opt == "optional":
+				dep.optional = true
+			case strings.HasPrefix(opt, "name="):
+				dep.name = strings.TrimPrefix(opt, "name=")
+			case strings.HasPrefix(opt, "value="), strings.HasPrefix(opt, "default="):
+				p.errorf(arg.Pos(), "Value field %v.%s isn't supported in a container", impl, f.Name())
+				valid = false
+			default:
+				p.errorf(arg.Pos(), "Invalid godi tag on %v.%s: unknown option '%s'", impl, f.Name(), opt)
+				valid = false
+			}
+		}
+		if valid && p.checkDep(dep) {
+			b.deps = append(b.deps, dep)
+		}
+	}
+
+	// GodiInit is called like godi does, if *impl has it.
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(impl), true, impl.Obj().Pkg(), "GodiInit")
+	if fn, ok := obj.(*types.Func); ok {
+		sig := fn.Type().(*types.Signature)
+		b.initialize = sig.Params().Len() == 0 && sig.Results().Len() == 1 && isError(sig.Results().At(0).Type())
+	}
+	return true
+}
+
+// parseProvider reads a provider function and its parameters.
+func (p *containerGen) parseProvider(b *staticBinding, arg ast.Expr) bool {
+	var id *ast.Ident
+	switch e := arg.(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	}
+	fn, _ := p.info.Uses[id].(*types.Func)
+	if id == nil || fn == nil || fn.Type().(*types.Signature).Recv() != nil {
+		p.errorf(arg.Pos(), "Providers in a container must be named functions")
+		return false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	results := sig.Results()
+	switch {
+	case sig.Variadic():
+		p.errorf(arg.Pos(), "Provider %s can't be variadic", fn.Name())
+		return false
+	case results.Len() == 1 && !isError(results.At(0).Type()):
+	case results.Len() == 2 && isError(results.At(1).Type()):
+		b.returnsErr = true
+	default:
+		p.errorf(arg.Pos(), "Provider %s must return a value, or a value and an error", fn.Name())
+		return false
+	}
+	b.provider = fn
+
+	for i := 0; i < sig.Params().Len(); i++ {
+		dep := &staticDep{t: sig.Params().At(i).Type(), pos: arg.Pos()}
+		if p.checkDep(dep) {
+			b.deps = append(b.deps, dep)
+		}
+	}
+	return true
+}
+
+// checkDep reports dependencies a container can't satisfy.
+func (p *containerGen) checkDep(dep *staticDep) bool {
+	t := dep.t
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		p.errorf(dep.pos, "Dependency %v must be a named type", dep.t)
+		return false
+	}
+	for _, wrapper := range []string{"Lazy", "Provider", "Optional"} {
+		if isGodi(named.Obj(), wrapper) {
+			p.errorf(dep.pos, "godi.%s dependencies aren't supported in a container", wrapper)
+			return false
+		}
+	}
+	return true
+}
+
+// checkInstance reports instance expressions that can't be moved out of the
+// spec function.
+func (p *containerGen) checkInstance(expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		switch obj := p.info.Uses[id].(type) {
+		case nil, *types.PkgName:
+		default:
+			if scope := obj.Parent(); scope != nil && scope != p.pkg.Scope() && scope != types.Universe {
+				p.errorf(id.Pos(), "Instances in a container can't refer to local %s", id.Name)
+			}
+		}
+		return true
+	})
+}
+
+// setResult works out the type of the binding's method, and checks the
+// implementor satisfies the target.
+func (p *containerGen) setResult(b *staticBinding) bool {
+	var produced types.Type
+	switch b.kind {
+	case bindType:
+		produced = types.NewPointer(b.impl)
+	case bindProvider:
+		produced = b.provider.Type().(*types.Signature).Results().At(0).Type()
+	case bindInstance:
+		produced = p.info.TypeOf(b.instance)
+	}
+
+	if types.IsInterface(b.target) {
+		if !types.AssignableTo(produced, b.target) {
+			p.errorf(b.pos, "%s does not implement %s", p.relative(produced), p.relative(b.target))
+			return false
+		}
+		b.result = b.target
+		return true
+	}
+
+	if !types.Identical(produced, b.target) && !types.Identical(produced, types.NewPointer(b.target)) {
+		p.errorf(b.pos, "%s can't be registered for %s", p.relative(produced), p.relative(b.target))
+		return false
+	}
+	b.result = produced
+	return true
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// key identifies a binding by target and name, the way godi looks them up.
+func key(t types.Type, name string) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	return types.TypeString(t, nil) + "|" + name
+}
+
+// check matches each dependency to a binding, and reports missing bindings,
+// cycles, and clashing method names.
+func (p *containerGen) check(ctr *container) {
+	byKey := map[string]*staticBinding{}
+	methods := map[string]*staticBinding{}
+	for _, b := range ctr.bindings {
+		k := key(b.target, b.name)
+		if prev := byKey[k]; prev != nil {
+			p.errorf(b.pos, "%s is already bound at %v", p.describe(b), p.fset.Position(prev.pos))
+			continue
+		}
+		byKey[k] = b
+
+		b.method = p.methodName(b)
+		if prev := methods[b.method]; prev != nil {
+			p.errorf(b.pos, "%s and %s would both be generated as %s", p.describe(prev), p.describe(b), b.method)
+		}
+		methods[b.method] = b
+	}
+
+	for _, b := range ctr.bindings {
+		for _, dep := range b.deps {
+			dep.binding = byKey[key(dep.t, dep.name)]
+			switch {
+			case dep.binding == nil && !dep.optional:
+				p.errorf(dep.pos, "%s depends on %s, which isn't bound in %s", p.describe(b), p.describeDep(dep), ctr.name)
+			case dep.binding != nil && p.depExpr(dep, "v") == "":
+				p.errorf(dep.pos, "%s depends on %s, but %s returns %s", p.describe(b), p.describeDep(dep),
+					p.describe(dep.binding), p.relative(dep.binding.result))
+			}
+		}
+	}
+
+	// depth-first search for cycles
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[*staticBinding]int{}
+	var path []*staticBinding
+	var visit func(b *staticBinding) bool
+	visit = func(b *staticBinding) bool {
+		switch state[b] {
+		case visiting:
+			var names []string
+			for i := len(path) - 1; i >= 0; i-- {
+				names = append([]string{p.describe(path[i])}, names...)
+				if path[i] == b {
+					break
+				}
+			}
+			names = append(names, p.describe(b))
+			p.errorf(b.pos, "Dependency cycle: %s", strings.Join(names, " -> "))
+			return false
+		case visited:
+			return true
+		}
+		state[b] = visiting
+		path = append(path, b)
+		for _, dep := range b.deps {
+			if dep.binding != nil && !visit(dep.binding) {
+				return false
+			}
+		}
+		path = path[:len(path)-1]
+		state[b] = visited
+		return true
+	}
+	for _, b := range ctr.bindings {
+		if !visit(b) {
+			return
+		}
+	}
+}
+
+// relative returns the name of t, without the package qualifier if it is
+// declared in the package being generated.
+func (p *containerGen) relative(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(p.pkg))
+}
+
+func (p *containerGen) describe(b *staticBinding) string {
+	s := p.relative(b.target)
+	if b.name != "" {
+		s += fmt.Sprintf(" (named %q)", b.name)
+	}
+	return s
+}
+
+func (p *containerGen) describeDep(dep *staticDep) string {
+	s := p.relative(dep.t)
+	if dep.name != "" {
+		s += fmt.Sprintf(" (named %q)", dep.name)
+	}
+	return s
+}
+
+// methodName returns the name of the method for b: the target's name,
+// qualified by its package if it is imported, followed by the registration
+// name.
+func (p *containerGen) methodName(b *staticBinding) string {
+	obj := b.target.Obj()
+	name := exportedName(obj.Name())
+	if obj.Pkg() != p.pkg {
+		name = exportedName(obj.Pkg().Name()) + name
+	}
+	name += exportedName(b.name)
+	if p.reserved[name] {
+		name += "Instance"
+	}
+	return name
+}
+
+// exportedName turns s into an exported identifier, dropping characters
+// that can't be in one and upper casing the letters after them.
+func exportedName(s string) string {
+	var out strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// depExpr returns the expression that passes variable v, holding the
+// result of dep's binding, as dep.  Returns "" if it can't.
+func (p *containerGen) depExpr(dep *staticDep, v string) string {
+	result := dep.binding.result
+	if types.AssignableTo(result, dep.t) {
+		return v
+	}
+	if ptr, ok := result.(*types.Pointer); ok && types.AssignableTo(ptr.Elem(), dep.t) {
+		return "*" + v
+	}
+	return ""
+}
+
+// --------
+//
+// Code generation
+//
+// --------
+
+func (p *containerGen) qualifier(pkg *types.Package) string {
+	if pkg == p.pkg {
+		return ""
+	}
+	p.imports[[2]string{pkg.Name(), pkg.Path()}] = true
+	return pkg.Name()
+}
+
+func (p *containerGen) typeString(t types.Type) string {
+	return types.TypeString(t, p.qualifier)
+}
+
+// typeName returns the name of the godi type for t, as godi would report it.
+func typeName(t *types.Named) string {
+	return t.Obj().Pkg().Name() + "." + t.Obj().Name()
+}
+
+// expr prints an expression from the spec, importing the packages it uses.
+func (p *containerGen) expr(e ast.Expr) string {
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if pkgName, ok := p.info.Uses[id].(*types.PkgName); ok {
+				p.imports[[2]string{pkgName.Name(), pkgName.Imported().Path()}] = true
+			}
+		}
+		return true
+	})
+
+	var buf bytes.Buffer
+	printer.Fprint(&buf, p.fset, e)
+	return buf.String()
+}
+
+func (p *containerGen) source(containers []*container) ([]byte, error) {
+	var body bytes.Buffer
+	godi := p.qualifier(types.NewPackage(godiPath, "godi"))
+
+	for _, ctr := range containers {
+		p.writeContainer(&body, ctr, godi)
+	}
+
+	fmt.Fprintf(&body, "\n// %sResolved returns a binding's result the way Resolve does.\n", godi)
+	fmt.Fprintf(&body, "func %sResolved[T any](v T, err error) (interface{}, error) {\n", godi)
+	body.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n\treturn v, nil\n}\n")
+
+	if p.optional {
+		fmt.Fprintf(&body, "\n// %sOptional resolves an optional dependency that isn't generated from\n// scope, returning the zero value if nothing is registered for it.\n", godi)
+		fmt.Fprintf(&body, "func %sOptional[T any](scope %s.RegistrationContext, name string) (T, error) {\n", godi, godi)
+		fmt.Fprintf(&body, "\tvar v T\n\traw, err := scope.ResolveNamed((*%s.Optional[T])(nil), name)\n", godi)
+		fmt.Fprintf(&body, "\tif o, ok := raw.(%s.Optional[T]); ok {\n\t\tv, _ = o.Get()\n\t}\n\treturn v, err\n}\n", godi)
+	}
+
+	for _, ctr := range containers {
+		if ctr.hasCached() {
+			p.imports[[2]string{"sync", "sync"}] = true
+			break
+		}
+	}
+
+	// standard library imports first, as goimports groups them
+	var std, other []string
+	for imp := range p.imports {
+		spec := fmt.Sprintf("%q", imp[1])
+		if pkgBase(imp[1]) != imp[0] {
+			spec = imp[0] + " " + spec
+		}
+		if strings.Contains(strings.Split(imp[1], "/")[0], ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by godi-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", p.pkg.Name())
+	for _, imp := range std {
+		fmt.Fprintf(&src, "\t%s\n", imp)
+	}
+	if len(std) > 0 && len(other) > 0 {
+		src.WriteString("\n")
+	}
+	for _, imp := range other {
+		fmt.Fprintf(&src, "\t%s\n", imp)
+	}
+	src.WriteString(")\n")
+	src.Write(body.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+func pkgBase(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func (p *containerGen) writeContainer(w *bytes.Buffer, ctr *container, godi string) {
+	fmt.Fprintf(w, "\n// %s is generated from the bindings in %s.\n", ctr.name, ctr.spec)
+	fmt.Fprintf(w, "// It builds them with plain Go code, and resolves anything else from its\n// scope.\n")
+	fmt.Fprintf(w, "type %s struct {\n\t%s.RegistrationContext\n", ctr.name, godi)
+	for _, b := range ctr.bindings {
+		if b.cached {
+			f := lowerFirst(b.method)
+			fmt.Fprintf(w, "\n\t%sLock sync.Mutex\n\t%sDone bool\n\t%s %s\n", f, f, f, p.typeString(b.result))
+		}
+	}
+	fmt.Fprintf(w, "}\n\nvar _ %s.RegistrationContext = (*%s)(nil)\n", godi, ctr.name)
+
+	fmt.Fprintf(w, "\n// New%s returns a new container.  Its scope is a child of parent, or\n", ctr.name)
+	fmt.Fprintf(w, "// of the current scope if parent is nil.  The generated bindings are also\n")
+	fmt.Fprintf(w, "// registered in the scope, so dynamic registrations can depend on them.\n")
+	fmt.Fprintf(w, "func New%s(parent %s.RegistrationContext) (*%s, error) {\n", ctr.name, godi, ctr.name)
+	fmt.Fprintf(w, "\tvar scope %s.RegistrationContext\n\tif parent != nil {\n\t\tscope = parent.CreateScope()\n\t} else {\n\t\tscope = %s.CreateScope(false)\n\t}\n", godi, godi)
+	fmt.Fprintf(w, "\tc := &%s{RegistrationContext: scope}\n", ctr.name)
+	for _, b := range ctr.bindings {
+		opts := ""
+		if b.name != "" {
+			opts = fmt.Sprintf(", %s.Named(%q)", godi, b.name)
+		}
+		fmt.Fprintf(w, "\tif _, err := scope.RegisterProvider((*%s)(nil), c.%s, false%s); err != nil {\n", p.typeString(b.target), b.method, opts)
+		w.WriteString("\t\tscope.Close()\n\t\treturn nil, err\n\t}\n")
+	}
+	w.WriteString("\treturn c, nil\n}\n")
+
+	for _, b := range ctr.bindings {
+		p.writeBinding(w, ctr, b, godi)
+	}
+
+	p.writeResolve(w, ctr, "Resolve", godi)
+	p.writeResolve(w, ctr, "TryResolve", godi)
+
+	var named []*staticBinding
+	for _, b := range ctr.bindings {
+		if b.name != "" {
+			named = append(named, b)
+		}
+	}
+	sort.SliceStable(named, func(i, j int) bool { return named[i].name < named[j].name })
+	if len(named) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n// ResolveNamed returns the generated named bindings directly, and resolves\n// anything else from the scope.\n")
+	fmt.Fprintf(w, "func (c *%s) ResolveNamed(target interface{}, name string) (interface{}, error) {\n\tswitch name {\n", ctr.name)
+	for i, b := range named {
+		if i == 0 || named[i-1].name != b.name {
+			if i > 0 {
+				w.WriteString("\t\t}\n")
+			}
+			fmt.Fprintf(w, "\tcase %q:\n\t\tswitch target.(type) {\n", b.name)
+		}
+		fmt.Fprintf(w, "\t\tcase %s:\n\t\t\treturn %sResolved(c.%s())\n", p.targetCases(b), godi, b.method)
+	}
+	w.WriteString("\t\t}\n\t}\n\treturn c.RegistrationContext.ResolveNamed(target, name)\n}\n")
+}
+
+// targetCases returns the type switch cases matching the target values godi
+// accepts for b's target.
+func (p *containerGen) targetCases(b *staticBinding) string {
+	t := p.typeString(b.target)
+	if types.IsInterface(b.target) {
+		return "*" + t
+	}
+	return t + ", *" + t
+}
+
+func (p *containerGen) writeResolve(w *bytes.Buffer, ctr *container, method, godi string) {
+	fmt.Fprintf(w, "\n// %s returns the generated bindings directly, and resolves anything else\n// from the scope.\n", method)
+	fmt.Fprintf(w, "func (c *%s) %s(target interface{}) (interface{}, error) {\n\tswitch target.(type) {\n", ctr.name, method)
+	for _, b := range ctr.bindings {
+		if b.name == "" {
+			fmt.Fprintf(w, "\tcase %s:\n\t\treturn %sResolved(c.%s())\n", p.targetCases(b), godi, b.method)
+		}
+	}
+	fmt.Fprintf(w, "\t}\n\treturn c.RegistrationContext.%s(target)\n}\n", method)
+}
+
+func (p *containerGen) writeBinding(w *bytes.Buffer, ctr *container, b *staticBinding, godi string) {
+	result := p.typeString(b.result)
+	f := lowerFirst(b.method)
+
+	if b.cached {
+		fmt.Fprintf(w, "\n// %s returns the %s binding, creating it on the first call.\n", b.method, p.describe(b))
+		fmt.Fprintf(w, "func (c *%s) %s() (%s, error) {\n", ctr.name, b.method, result)
+		fmt.Fprintf(w, "\tc.%sLock.Lock()\n\tdefer c.%sLock.Unlock()\n", f, f)
+		fmt.Fprintf(w, "\tif !c.%sDone {\n\t\tv, err := c.new%s()\n\t\tif err != nil {\n\t\t\treturn v, err\n\t\t}\n", f, b.method)
+		fmt.Fprintf(w, "\t\tc.%s, c.%sDone = v, true\n\t}\n\treturn c.%s, nil\n}\n", f, f, f)
+	} else {
+		fmt.Fprintf(w, "\n// %s returns a new %s binding.\n", b.method, p.describe(b))
+		fmt.Fprintf(w, "func (c *%s) %s() (%s, error) {\n\treturn c.new%s()\n}\n", ctr.name, b.method, result, b.method)
+	}
+
+	fmt.Fprintf(w, "\nfunc (c *%s) new%s() (v %s, err error) {\n", ctr.name, b.method, result)
+	var args []string
+	for i, dep := range b.deps {
+		v := fmt.Sprintf("d%d", i)
+		if dep.binding == nil {
+			p.optional = true
+			fmt.Fprintf(w, "\t%s, err := %sOptional[%s](c.RegistrationContext, %q)\n\tif err != nil {\n\t\treturn\n\t}\n",
+				v, godi, p.typeString(dep.t), dep.name)
+			args = append(args, v)
+			continue
+		}
+		fmt.Fprintf(w, "\t%s, err := c.%s()\n\tif err != nil {\n\t\treturn\n\t}\n", v, dep.binding.method)
+		args = append(args, p.depExpr(dep, v))
+	}
+
+	initErr := func(err string) {
+		fmt.Fprintf(w, "\t\terr = &%s.InitError{TypeName: %q, Target: %q, Err: %s}\n\t\treturn\n\t}\n", godi, p.implName(b), typeName(b.target), err)
+	}
+
+	switch b.kind {
+	case bindType:
+		fmt.Fprintf(w, "\tinst := &%s{}\n", p.typeString(b.impl))
+		for i, dep := range b.deps {
+			fmt.Fprintf(w, "\tinst.%s = %s\n", dep.field, args[i])
+		}
+		if b.initialize {
+			w.WriteString("\tif err = inst.GodiInit(); err != nil {\n")
+			initErr("err")
+		}
+		w.WriteString("\treturn inst, nil\n}\n")
+
+	case bindProvider:
+		call := fmt.Sprintf("%s(%s)", p.funcName(b.provider), strings.Join(args, ", "))
+		if !b.returnsErr {
+			fmt.Fprintf(w, "\treturn %s, nil\n}\n", call)
+			return
+		}
+		fmt.Fprintf(w, "\tr, perr := %s\n\tif perr != nil {\n", call)
+		initErr("perr")
+		w.WriteString("\treturn r, nil\n}\n")
+
+	case bindInstance:
+		fmt.Fprintf(w, "\treturn %s, nil\n}\n", p.expr(b.instance))
+	}
+}
+
+// implName returns the implementor's name as godi would report it.
+func (p *containerGen) implName(b *staticBinding) string {
+	t := p.typeStringFor(b)
+	if named, ok := t.(*types.Named); ok {
+		return typeName(named)
+	}
+	return t.String()
+}
+
+func (p *containerGen) typeStringFor(b *staticBinding) types.Type {
+	if b.kind == bindType {
+		return b.impl
+	}
+	t := b.provider.Type().(*types.Signature).Results().At(0).Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
+
+func (p *containerGen) funcName(fn *types.Func) string {
+	if q := p.qualifier(fn.Pkg()); q != "" {
+		return q + "." + fn.Name()
+	}
+	return fn.Name()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGenerateContainer(t *testing.T) {
+	g := &generator{dir: "testdata/app", output: containerOutput, containers: true}
+	src, err := g.generate()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	golden := filepath.Join(g.dir, containerOutput)
+	if *update {
+		if err := os.WriteFile(golden, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	assert.Nil(t, err)
+	assert.Equal(t, string(want), string(src))
+}
+
+// TestGeneratedContainer runs the tests in testdata/app against the golden
+// container, which check that it builds the same graph as its spec run
+// against a dynamic scope.
+func TestGeneratedContainer(t *testing.T) {
+	out, err := exec.Command("go", "test", "./testdata/app").CombinedOutput()
+	assert.Nil(t, err, string(out))
+}
+
+func TestGenerateContainerErrors(t *testing.T) {
+	g := &generator{dir: "testdata/bad", output: containerOutput, containers: true}
+	_, err := g.generate()
+	if !assert.NotNil(t, err) {
+		return
+	}
+
+	for _, msg := range []string{
+		"A depends on B, which isn't bound in Missing",
+		"Dependency cycle: A -> B -> A",
+		"invalid must take a single godi.RegistrationContext",
+		"*NotA does not implement A",
+		"cached must be a constant",
+		"InitializeCallbacks aren't supported",
+		"can't refer to local local",
+		"godi.Lazy dependencies aren't supported",
+		"Value field bad.TValue.Timeout isn't supported",
+		"RegisterHook isn't supported",
+		"bad.go:66:2: A is already bound at",
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+	assert.NotContains(t, err.Error(), "time.Duration")
+
+	g = &generator{dir: "testdata/zoo", output: containerOutput, containers: true}
+	_, err = g.generate()
+	assert.EqualError(t, err, "No //godi:container functions in package zoo")
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultOutput   = "godi_registry_gen.go"
+	containerOutput = "godi_container_gen.go"
+	directive       = "//godi:register"
+	godiPath        = "github.com/shawnburke/godi"
+)
+
+type generator struct {
+	dir        string
+	output     string
+	implements []string
+	bind       bool
+	cached     bool
+	containers bool
+}
+
+// binding is a RegisterTypeImplementor call to generate.
+type binding struct {
+	target    *types.TypeName
+	impl      *types.TypeName
+	cached    bool
+	name      string
+	isDefault bool
+}
+
+// registry is what gets generated for a package.
+type registry struct {
+	pkg      *types.Package
+	types    map[*types.TypeName]bool
+	bindings []binding
+	imports  map[string]string
+}
+
+// generate reads the package in g.dir and returns the formatted source of its
+// registry file.
+func (g *generator) generate() ([]byte, error) {
+	fset := token.NewFileSet()
+	pkg, files, info, err := g.load(fset)
+	if err != nil {
+		return nil, err
+	}
+	if g.containers {
+		return generateContainers(fset, pkg, files, info)
+	}
+
+	r := &registry{pkg: pkg, types: map[*types.TypeName]bool{}, imports: map[string]string{}}
+	for _, f := range files {
+		if err := r.addDirectives(fset, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.addImplementors(g.implements, g.bind, g.cached); err != nil {
+		return nil, err
+	}
+	return r.source()
+}
+
+// load parses and type-checks the package in g.dir, leaving out test files
+// and generated files.
+func (g *generator) load(fset *token.FileSet) (*types.Package, []*ast.File, *types.Info, error) {
+	entries, err := os.ReadDir(g.dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == g.output || name == defaultOutput || name == containerOutput {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(g.dir, name); err != nil || !ok {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(g.dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(files) > 0 && f.Name.Name != files[0].Name.Name {
+			return nil, nil, nil, fmt.Errorf("Found packages %s and %s in %s", files[0].Name.Name, f.Name.Name, g.dir)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, fmt.Errorf("No Go files in %s", g.dir)
+	}
+
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+		Uses:  map[*ast.Ident]types.Object{},
+		Defs:  map[*ast.Ident]types.Object{},
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check(files[0].Name.Name, fset, files, info)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pkg, files, info, nil
+}
+
+// addDirectives adds the types in f marked with //godi:register.
+func (p *registry) addDirectives(fset *token.FileSet, f *ast.File) error {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			doc := ts.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			if doc == nil {
+				continue
+			}
+			for _, c := range doc.List {
+				opts, ok := strings.CutPrefix(c.Text, directive)
+				if !ok || (opts != "" && opts[0] != ' ' && opts[0] != '\t') {
+					continue
+				}
+				if err := p.addDirective(ts, opts); err != nil {
+					return fmt.Errorf("%v: %w", fset.Position(c.Pos()), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *registry) addDirective(ts *ast.TypeSpec, opts string) error {
+	if ts.TypeParams != nil {
+		return fmt.Errorf("Generic type %s can't be registered", ts.Name.Name)
+	}
+	impl := p.pkg.Scope().Lookup(ts.Name.Name).(*types.TypeName)
+	p.types[impl] = true
+
+	var targets []*types.TypeName
+	var b binding
+	for _, opt := range strings.Fields(opts) {
+		switch {
+		case opt == "cached":
+			b.cached = true
+		case opt == "default":
+			b.isDefault = true
+		case strings.HasPrefix(opt, "name="):
+			b.name = strings.TrimPrefix(opt, "name=")
+		case strings.HasPrefix(opt, "bind="):
+			target, err := p.lookup(strings.TrimPrefix(opt, "bind="))
+			if err != nil {
+				return err
+			}
+			targets = append(targets, target)
+		default:
+			return fmt.Errorf("Unknown option '%s'", opt)
+		}
+	}
+	if len(targets) == 0 && (b.cached || b.isDefault || b.name != "") {
+		return fmt.Errorf("cached, default and name= require bind=<Target>")
+	}
+
+	for _, target := range targets {
+		if !implements(impl, target) {
+			return fmt.Errorf("%s does not implement %s", impl.Name(), target.Name())
+		}
+		b.target, b.impl = target, impl
+		p.addBinding(b)
+	}
+	return nil
+}
+
+// addImplementors adds the exported types that implement any of the named
+// interfaces, binding them to the interfaces if bind is set and a directive
+// hasn't already.
+func (p *registry) addImplementors(names []string, bind, cached bool) error {
+	for _, name := range names {
+		iface, err := p.lookup(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		if !types.IsInterface(iface.Type()) {
+			return fmt.Errorf("%s is not an interface", name)
+		}
+
+		scope := p.pkg.Scope()
+		for _, n := range scope.Names() {
+			impl, ok := scope.Lookup(n).(*types.TypeName)
+			if !ok || !impl.Exported() || impl.IsAlias() || types.IsInterface(impl.Type()) {
+				continue
+			}
+			if named, ok := impl.Type().(*types.Named); !ok || named.TypeParams() != nil {
+				continue
+			}
+			if !implements(impl, iface) {
+				continue
+			}
+			p.types[impl] = true
+			if bind && !p.bound(iface, impl) {
+				p.addBinding(binding{target: iface, impl: impl, cached: cached})
+			}
+		}
+	}
+	return nil
+}
+
+func (p *registry) addBinding(b binding) {
+	if b.target.Pkg() == p.pkg {
+		p.types[b.target] = true
+	}
+	for _, existing := range p.bindings {
+		if existing == b {
+			return
+		}
+	}
+	p.bindings = append(p.bindings, b)
+}
+
+// bound returns true if a directive already binds impl to target.
+func (p *registry) bound(target, impl *types.TypeName) bool {
+	for _, b := range p.bindings {
+		if b.target == target && b.impl == impl {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds a type by name in the package, or as <package>.<Type> in one
+// of its imports, by package name or import path.
+func (p *registry) lookup(name string) (*types.TypeName, error) {
+	scope := p.pkg.Scope()
+	qual, typeName, qualified := cutLast(name, ".")
+	if qualified {
+		scope = nil
+		for _, imp := range p.pkg.Imports() {
+			if imp.Name() == qual || imp.Path() == qual {
+				scope = imp.Scope()
+				break
+			}
+		}
+		if scope == nil {
+			return nil, fmt.Errorf("Can't find package '%s' for %s, is it imported?", qual, name)
+		}
+	}
+
+	obj, ok := scope.Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("Can't find type %s", name)
+	}
+	return obj, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
+
+// implements returns true if impl, or a pointer to it, implements the target
+// interface.
+func implements(impl, target *types.TypeName) bool {
+	iface, ok := target.Type().Underlying().(*types.Interface)
+	if !ok {
+		return false
+	}
+	return types.Implements(impl.Type(), iface) || types.Implements(types.NewPointer(impl.Type()), iface)
+}
+
+// expr returns the expression godi takes for obj: a zero struct for structs,
+// and a nil pointer for anything else.
+func (p *registry) expr(obj *types.TypeName) string {
+	name := obj.Name()
+	if obj.Pkg() != p.pkg {
+		p.imports[obj.Pkg().Path()] = obj.Pkg().Name()
+		name = obj.Pkg().Name() + "." + name
+	}
+	if _, ok := obj.Type().Underlying().(*types.Struct); ok {
+		return name + "{}"
+	}
+	return "(*" + name + ")(nil)"
+}
+
+// source returns the formatted registry file.
+func (p *registry) source() ([]byte, error) {
+	var registered []*types.TypeName
+	for t := range p.types {
+		registered = append(registered, t)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name() < registered[j].Name() })
+
+	var body bytes.Buffer
+	for _, t := range registered {
+		fmt.Fprintf(&body, "\tgodi.RegisterType(%s)\n", p.expr(t))
+	}
+	if len(p.bindings) > 0 {
+		body.WriteString("\n")
+	}
+	for _, b := range p.bindings {
+		opts := ""
+		if b.name != "" {
+			opts += fmt.Sprintf(", godi.Named(%q)", b.name)
+		}
+		if b.isDefault {
+			opts += ", godi.AsDefault()"
+		}
+		fmt.Fprintf(&body, "\tif _, err := godi.RegisterTypeImplementor(%s, %s, %v, nil%s); err != nil {\n\t\tpanic(err)\n\t}\n",
+			p.expr(b.target), p.expr(b.impl), b.cached, opts)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by godi-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", p.pkg.Name())
+	fmt.Fprintf(&src, "\t%q\n", godiPath)
+	for path := range p.imports {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	fmt.Fprintf(&src, ")\n\nfunc init() {\n%s}\n", body.String())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Formatting generated code: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const zooDirectives = `// Code generated by godi-gen. DO NOT EDIT.
+
+package zoo
+
+import (
+	"github.com/shawnburke/godi"
+	"io"
+)
+
+func init() {
+	godi.RegisterType((*Animal)(nil))
+	godi.RegisterType((*Celsius)(nil))
+	godi.RegisterType(Hippo{})
+	godi.RegisterType(Stream{})
+	godi.RegisterType(Zebra{})
+
+	if _, err := godi.RegisterTypeImplementor((*Animal)(nil), Hippo{}, true, nil); err != nil {
+		panic(err)
+	}
+	if _, err := godi.RegisterTypeImplementor((*Animal)(nil), Zebra{}, false, nil, godi.Named("zebra"), godi.AsDefault()); err != nil {
+		panic(err)
+	}
+	if _, err := godi.RegisterTypeImplementor((*io.Reader)(nil), Stream{}, false, nil); err != nil {
+		panic(err)
+	}
+}
+`
+
+func TestGenerateDirectives(t *testing.T) {
+	g := &generator{dir: "testdata/zoo", output: defaultOutput}
+	src, err := g.generate()
+	assert.Nil(t, err)
+	assert.Equal(t, zooDirectives, string(src))
+}
+
+func TestGenerateImplements(t *testing.T) {
+	g := &generator{dir: "testdata/zoo", output: defaultOutput, implements: []string{"Animal"}, bind: true}
+	src, err := g.generate()
+	assert.Nil(t, err)
+
+	// Lion is found, but the unexported lynx isn't
+	assert.Contains(t, string(src), "godi.RegisterType(Lion{})")
+	assert.Contains(t, string(src), "godi.RegisterTypeImplementor((*Animal)(nil), Lion{}, false, nil)")
+	assert.NotContains(t, string(src), "lynx")
+
+	// the bindings from directives aren't repeated
+	assert.Contains(t, string(src), "godi.RegisterTypeImplementor((*Animal)(nil), Hippo{}, true, nil)")
+	assert.Equal(t, 1, strings.Count(string(src), "(*Animal)(nil), Hippo{}"))
+	assert.Equal(t, 1, strings.Count(string(src), "(*Animal)(nil), Zebra{}"))
+}
+
+func TestGenerateSkipsOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.go", "package a\n\n//godi:register\ntype A struct{}\n")
+	writeFile(t, dir, defaultOutput, "package a\n\nthis is not go\n")
+	writeFile(t, dir, "a_test.go", "package a_test\n")
+
+	g := &generator{dir: dir, output: defaultOutput}
+	src, err := g.generate()
+	assert.Nil(t, err)
+	assert.Contains(t, string(src), "godi.RegisterType(A{})")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"type I interface{ F() }\n\n//godi:register bind=I\ntype A struct{}\n", "A does not implement I"},
+		{"//godi:register bind=Missing\ntype A struct{}\n", "Can't find type Missing"},
+		{"//godi:register bind=io.Reader\ntype A struct{}\n", "Can't find package 'io'"},
+		{"//godi:register cached\ntype A struct{}\n", "require bind="},
+		{"//godi:register shiny\ntype A struct{}\n", "Unknown option 'shiny'"},
+		{"//godi:register\ntype A[T any] struct{}\n", "Generic type A"},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.go", "package a\n\n"+c.src)
+
+		g := &generator{dir: dir, output: defaultOutput}
+		_, err := g.generate()
+		if assert.NotNil(t, err, c.src) {
+			assert.Contains(t, err.Error(), c.err)
+		}
+	}
+
+	g := &generator{dir: "testdata/zoo", output: defaultOutput, implements: []string{"Hippo"}}
+	_, err := g.generate()
+	assert.EqualError(t, err, "Hippo is not an interface")
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+// Command godi-gen writes the RegisterType calls for a package, so that its
+// types can be used with name-based registrations without maintaining an
+// init() by hand.  It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/shawnburke/godi/cmd/godi-gen
+//
+// Types are selected with a //godi:register comment:
+//
+//	//godi:register bind=Animal cached
+//	type Hippo struct{}
+//
+// which registers Hippo, and with bind=, also registers it as an implementor
+// of the target.  The options are:
+//
+//	bind=<Target>  register the type as an implementor of Target, which is a
+//	               type in this package or an imported one, e.g. io.Reader
+//	cached         make the binding cached
+//	name=<name>    make the binding named
+//	default        make the binding a default, see godi.AsDefault
+//
+// With -implements, every exported type implementing one of the listed
+// interfaces is registered too, and with -bind, bound to it.
+//
+// The generated file is written to godi_registry_gen.go in the package
+// directory, and is ignored when the package is read, so it can be
+// regenerated freely.
+//
+// With -container, godi-gen instead writes godi_container_gen.go, with a
+// reflection-free container for each function marked
+// //godi:container <Name>, built from the registrations the function makes.
+// See container.go.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("godi-gen: ")
+
+	g := &generator{}
+	var implements string
+	flag.StringVar(&g.dir, "dir", ".", "the package directory")
+	flag.StringVar(&g.output, "output", defaultOutput, "the file to write, relative to -dir")
+	flag.StringVar(&implements, "implements", "", "comma-separated interfaces whose exported implementors are registered, e.g. Animal,io.Reader")
+	flag.BoolVar(&g.bind, "bind", false, "bind the types found with -implements to the interfaces they implement")
+	flag.BoolVar(&g.cached, "cached", false, "make the -bind bindings cached")
+	flag.BoolVar(&g.containers, "container", false, "generate the containers declared by //godi:container functions instead")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: godi-gen [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if g.containers && g.output == defaultOutput {
+		g.output = containerOutput
+	}
+	if implements != "" {
+		g.implements = strings.Split(implements, ",")
+	}
+	if g.bind && len(g.implements) == 0 {
+		log.Fatal("-bind requires -implements")
+	}
+
+	src, err := g.generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(g.dir, g.output), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
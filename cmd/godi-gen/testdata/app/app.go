@@ -0,0 +1,76 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/shawnburke/godi"
+)
+
+type (
+	Animal interface {
+		Name() string
+	}
+
+	Keeper interface {
+		Feed(Animal) string
+	}
+
+	Hippo struct {
+		Keeper Keeper `godi:""`
+		backup Keeper `godi:"name=backup,optional"`
+		vet    Vet    `godi:"optional"`
+
+		inits int
+	}
+
+	Vet interface {
+		Heal()
+	}
+
+	keeper struct {
+		name string
+		food io.Reader
+	}
+
+	Zoo struct {
+		Animals []Animal
+	}
+)
+
+var defaultReader = strings.NewReader("hay")
+
+func (p *Hippo) Name() string { return "hippo" }
+
+func (p *Hippo) GodiInit() error {
+	p.inits++
+	if p.Keeper == nil {
+		return errors.New("no keeper")
+	}
+	return nil
+}
+
+func (p *keeper) Feed(a Animal) string { return p.name + " feeds the " + a.Name() }
+
+func NewKeeper(food io.Reader) Keeper {
+	return &keeper{name: "keeper", food: food}
+}
+
+func NewBackupKeeper() (*keeper, error) {
+	return &keeper{name: "backup"}, nil
+}
+
+func NewZoo(a Animal, h *Hippo) *Zoo {
+	return &Zoo{Animals: []Animal{a, h}}
+}
+
+//godi:container AppContainer
+func appBindings(c godi.RegistrationContext) {
+	c.RegisterTypeImplementor((*Animal)(nil), Hippo{}, true, nil)
+	c.RegisterTypeImplementor(Hippo{}, Hippo{}, false, nil)
+	c.RegisterProvider((*Keeper)(nil), NewKeeper, false)
+	c.RegisterProvider((*Keeper)(nil), NewBackupKeeper, true, godi.Named("backup"))
+	c.RegisterInstanceImplementor((*io.Reader)(nil), defaultReader)
+	c.RegisterProvider(Zoo{}, NewZoo, true)
+}
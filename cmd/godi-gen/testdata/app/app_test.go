@@ -0,0 +1,58 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/shawnburke/godi"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVet struct{}
+
+func (p *testVet) Heal() {}
+
+func TestContainerMatchesScope(t *testing.T) {
+	vet := &testVet{}
+	parent := godi.CreateScope(false)
+	defer parent.Close()
+	parent.RegisterInstanceImplementor((*Vet)(nil), vet)
+
+	c, err := NewAppContainer(parent)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+
+	dynamic := parent.CreateScope()
+	defer dynamic.Close()
+	appBindings(dynamic)
+
+	for _, scope := range []godi.RegistrationContext{c, dynamic} {
+		zoo, err := godi.ResolveAs[*Zoo](scope)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		again, _ := godi.ResolveAs[*Zoo](scope)
+		assert.True(t, zoo == again)
+
+		hippo := zoo.Animals[1].(*Hippo)
+		assert.Equal(t, "keeper feeds the hippo", hippo.Keeper.Feed(zoo.Animals[0]))
+		assert.Equal(t, "backup", hippo.backup.(*keeper).name)
+		assert.True(t, hippo.vet == vet)
+		assert.Equal(t, 1, hippo.inits)
+
+		backup, err := scope.ResolveNamed((*Keeper)(nil), "backup")
+		assert.Nil(t, err)
+		assert.True(t, backup == hippo.backup)
+	}
+
+	// without a vet registered, the optional field is left alone
+	c, err = NewAppContainer(nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+	hippo, err := c.Hippo()
+	assert.Nil(t, err)
+	assert.Nil(t, hippo.vet)
+}
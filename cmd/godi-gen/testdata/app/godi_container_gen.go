@@ -0,0 +1,288 @@
+// Code generated by godi-gen. DO NOT EDIT.
+
+package app
+
+import (
+	"io"
+	"sync"
+
+	"github.com/shawnburke/godi"
+)
+
+// AppContainer is generated from the bindings in appBindings.
+// It builds them with plain Go code, and resolves anything else from its
+// scope.
+type AppContainer struct {
+	godi.RegistrationContext
+
+	animalLock sync.Mutex
+	animalDone bool
+	animal     Animal
+
+	keeperBackupLock sync.Mutex
+	keeperBackupDone bool
+	keeperBackup     Keeper
+
+	ioReaderLock sync.Mutex
+	ioReaderDone bool
+	ioReader     io.Reader
+
+	zooLock sync.Mutex
+	zooDone bool
+	zoo     *Zoo
+}
+
+var _ godi.RegistrationContext = (*AppContainer)(nil)
+
+// NewAppContainer returns a new container.  Its scope is a child of parent, or
+// of the current scope if parent is nil.  The generated bindings are also
+// registered in the scope, so dynamic registrations can depend on them.
+func NewAppContainer(parent godi.RegistrationContext) (*AppContainer, error) {
+	var scope godi.RegistrationContext
+	if parent != nil {
+		scope = parent.CreateScope()
+	} else {
+		scope = godi.CreateScope(false)
+	}
+	c := &AppContainer{RegistrationContext: scope}
+	if _, err := scope.RegisterProvider((*Animal)(nil), c.Animal, false); err != nil {
+		scope.Close()
+		return nil, err
+	}
+	if _, err := scope.RegisterProvider((*Hippo)(nil), c.Hippo, false); err != nil {
+		scope.Close()
+		return nil, err
+	}
+	if _, err := scope.RegisterProvider((*Keeper)(nil), c.Keeper, false); err != nil {
+		scope.Close()
+		return nil, err
+	}
+	if _, err := scope.RegisterProvider((*Keeper)(nil), c.KeeperBackup, false, godi.Named("backup")); err != nil {
+		scope.Close()
+		return nil, err
+	}
+	if _, err := scope.RegisterProvider((*io.Reader)(nil), c.IoReader, false); err != nil {
+		scope.Close()
+		return nil, err
+	}
+	if _, err := scope.RegisterProvider((*Zoo)(nil), c.Zoo, false); err != nil {
+		scope.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Animal returns the Animal binding, creating it on the first call.
+func (c *AppContainer) Animal() (Animal, error) {
+	c.animalLock.Lock()
+	defer c.animalLock.Unlock()
+	if !c.animalDone {
+		v, err := c.newAnimal()
+		if err != nil {
+			return v, err
+		}
+		c.animal, c.animalDone = v, true
+	}
+	return c.animal, nil
+}
+
+func (c *AppContainer) newAnimal() (v Animal, err error) {
+	d0, err := c.Keeper()
+	if err != nil {
+		return
+	}
+	d1, err := c.KeeperBackup()
+	if err != nil {
+		return
+	}
+	d2, err := godiOptional[Vet](c.RegistrationContext, "")
+	if err != nil {
+		return
+	}
+	inst := &Hippo{}
+	inst.Keeper = d0
+	inst.backup = d1
+	inst.vet = d2
+	if err = inst.GodiInit(); err != nil {
+		err = &godi.InitError{TypeName: "app.Hippo", Target: "app.Animal", Err: err}
+		return
+	}
+	return inst, nil
+}
+
+// Hippo returns a new Hippo binding.
+func (c *AppContainer) Hippo() (*Hippo, error) {
+	return c.newHippo()
+}
+
+func (c *AppContainer) newHippo() (v *Hippo, err error) {
+	d0, err := c.Keeper()
+	if err != nil {
+		return
+	}
+	d1, err := c.KeeperBackup()
+	if err != nil {
+		return
+	}
+	d2, err := godiOptional[Vet](c.RegistrationContext, "")
+	if err != nil {
+		return
+	}
+	inst := &Hippo{}
+	inst.Keeper = d0
+	inst.backup = d1
+	inst.vet = d2
+	if err = inst.GodiInit(); err != nil {
+		err = &godi.InitError{TypeName: "app.Hippo", Target: "app.Hippo", Err: err}
+		return
+	}
+	return inst, nil
+}
+
+// Keeper returns a new Keeper binding.
+func (c *AppContainer) Keeper() (Keeper, error) {
+	return c.newKeeper()
+}
+
+func (c *AppContainer) newKeeper() (v Keeper, err error) {
+	d0, err := c.IoReader()
+	if err != nil {
+		return
+	}
+	return NewKeeper(d0), nil
+}
+
+// KeeperBackup returns the Keeper (named "backup") binding, creating it on the first call.
+func (c *AppContainer) KeeperBackup() (Keeper, error) {
+	c.keeperBackupLock.Lock()
+	defer c.keeperBackupLock.Unlock()
+	if !c.keeperBackupDone {
+		v, err := c.newKeeperBackup()
+		if err != nil {
+			return v, err
+		}
+		c.keeperBackup, c.keeperBackupDone = v, true
+	}
+	return c.keeperBackup, nil
+}
+
+func (c *AppContainer) newKeeperBackup() (v Keeper, err error) {
+	r, perr := NewBackupKeeper()
+	if perr != nil {
+		err = &godi.InitError{TypeName: "app.keeper", Target: "app.Keeper", Err: perr}
+		return
+	}
+	return r, nil
+}
+
+// IoReader returns the io.Reader binding, creating it on the first call.
+func (c *AppContainer) IoReader() (io.Reader, error) {
+	c.ioReaderLock.Lock()
+	defer c.ioReaderLock.Unlock()
+	if !c.ioReaderDone {
+		v, err := c.newIoReader()
+		if err != nil {
+			return v, err
+		}
+		c.ioReader, c.ioReaderDone = v, true
+	}
+	return c.ioReader, nil
+}
+
+func (c *AppContainer) newIoReader() (v io.Reader, err error) {
+	return defaultReader, nil
+}
+
+// Zoo returns the Zoo binding, creating it on the first call.
+func (c *AppContainer) Zoo() (*Zoo, error) {
+	c.zooLock.Lock()
+	defer c.zooLock.Unlock()
+	if !c.zooDone {
+		v, err := c.newZoo()
+		if err != nil {
+			return v, err
+		}
+		c.zoo, c.zooDone = v, true
+	}
+	return c.zoo, nil
+}
+
+func (c *AppContainer) newZoo() (v *Zoo, err error) {
+	d0, err := c.Animal()
+	if err != nil {
+		return
+	}
+	d1, err := c.Hippo()
+	if err != nil {
+		return
+	}
+	return NewZoo(d0, d1), nil
+}
+
+// Resolve returns the generated bindings directly, and resolves anything else
+// from the scope.
+func (c *AppContainer) Resolve(target interface{}) (interface{}, error) {
+	switch target.(type) {
+	case *Animal:
+		return godiResolved(c.Animal())
+	case Hippo, *Hippo:
+		return godiResolved(c.Hippo())
+	case *Keeper:
+		return godiResolved(c.Keeper())
+	case *io.Reader:
+		return godiResolved(c.IoReader())
+	case Zoo, *Zoo:
+		return godiResolved(c.Zoo())
+	}
+	return c.RegistrationContext.Resolve(target)
+}
+
+// TryResolve returns the generated bindings directly, and resolves anything else
+// from the scope.
+func (c *AppContainer) TryResolve(target interface{}) (interface{}, error) {
+	switch target.(type) {
+	case *Animal:
+		return godiResolved(c.Animal())
+	case Hippo, *Hippo:
+		return godiResolved(c.Hippo())
+	case *Keeper:
+		return godiResolved(c.Keeper())
+	case *io.Reader:
+		return godiResolved(c.IoReader())
+	case Zoo, *Zoo:
+		return godiResolved(c.Zoo())
+	}
+	return c.RegistrationContext.TryResolve(target)
+}
+
+// ResolveNamed returns the generated named bindings directly, and resolves
+// anything else from the scope.
+func (c *AppContainer) ResolveNamed(target interface{}, name string) (interface{}, error) {
+	switch name {
+	case "backup":
+		switch target.(type) {
+		case *Keeper:
+			return godiResolved(c.KeeperBackup())
+		}
+	}
+	return c.RegistrationContext.ResolveNamed(target, name)
+}
+
+// godiResolved returns a binding's result the way Resolve does.
+func godiResolved[T any](v T, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// godiOptional resolves an optional dependency that isn't generated from
+// scope, returning the zero value if nothing is registered for it.
+func godiOptional[T any](scope godi.RegistrationContext, name string) (T, error) {
+	var v T
+	raw, err := scope.ResolveNamed((*godi.Optional[T])(nil), name)
+	if o, ok := raw.(godi.Optional[T]); ok {
+		v, _ = o.Get()
+	}
+	return v, err
+}
@@ -0,0 +1,67 @@
+package bad
+
+import (
+	"time"
+
+	"github.com/shawnburke/godi"
+)
+
+type (
+	A interface{ A() }
+	B interface{ B() }
+	C interface{ C() }
+
+	TA struct {
+		B B `godi:""`
+	}
+
+	TB struct {
+		A A `godi:""`
+	}
+
+	TValue struct {
+		Timeout time.Duration `godi:"value=timeout"`
+	}
+
+	NotA struct{}
+)
+
+func (p *TA) A()     {}
+func (p *TB) B()     {}
+func (p *TValue) A() {}
+
+func NewC(a godi.Lazy[A]) C { return nil }
+
+//godi:container Missing
+func missing(c godi.RegistrationContext) {
+	c.RegisterTypeImplementor((*A)(nil), TA{}, false, nil)
+}
+
+//godi:container Cycle
+func cycle(c godi.RegistrationContext) {
+	c.RegisterTypeImplementor((*A)(nil), TA{}, false, nil)
+	c.RegisterTypeImplementor((*B)(nil), TB{}, false, nil)
+}
+
+//godi:container Invalid
+func invalid(c godi.RegistrationContext, cached bool) {
+}
+
+//godi:container Unsupported
+func unsupported(c godi.RegistrationContext) {
+	cached := true
+	local := &TA{}
+	c.RegisterTypeImplementor((*A)(nil), NotA{}, false, nil)
+	c.RegisterTypeImplementor((*B)(nil), TB{}, cached, nil)
+	c.RegisterTypeImplementor((*B)(nil), TB{}, false, func(interface{}) (bool, error) { return true, nil })
+	c.RegisterInstanceImplementor((*A)(nil), local)
+	c.RegisterProvider((*C)(nil), NewC, false)
+	c.RegisterTypeImplementor((*A)(nil), TValue{}, false, nil)
+	c.RegisterHook(godi.HookOnError, nil)
+}
+
+//godi:container Duplicate
+func duplicate(c godi.RegistrationContext) {
+	c.RegisterInstanceImplementor((*A)(nil), &TA{})
+	c.RegisterInstanceImplementor((*A)(nil), &TA{})
+}
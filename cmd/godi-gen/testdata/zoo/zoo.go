@@ -0,0 +1,38 @@
+package zoo
+
+import "io"
+
+type Animal interface {
+	Name() string
+}
+
+//godi:register bind=Animal cached
+type Hippo struct{}
+
+func (Hippo) Name() string { return "hippo" }
+
+// Zebra has a pointer receiver.
+//
+//godi:register bind=Animal name=zebra default
+type Zebra struct{}
+
+func (*Zebra) Name() string { return "zebra" }
+
+type (
+	//godi:register bind=io.Reader
+	Stream struct{}
+
+	// Lion is only found with -implements.
+	Lion struct{}
+
+	lynx struct{}
+
+	//godi:register
+	Celsius float64
+)
+
+func (Stream) Read(b []byte) (int, error) { return 0, io.EOF }
+
+func (Lion) Name() string { return "lion" }
+
+func (lynx) Name() string { return "lynx" }
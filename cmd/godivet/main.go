@@ -0,0 +1,48 @@
+// Command godivet reports misuse of godi that would otherwise only fail at
+// runtime, such as asserting a Resolve result to a type that is never
+// registered.  See the godivet package for the checks.
+//
+//	godivet [dir ...]
+//
+// checks the packages in the directories, which default to ./... , and
+// exits with status 1 if it finds any problems.  Registrations are
+// collected from all of the packages checked, so check the whole program.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/shawnburke/godi/godivet"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("godivet: ")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: godivet [dir ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"./..."}
+	}
+
+	l := godivet.NewLoader()
+	pkgs, err := l.Load(dirs...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	diags := godivet.Check(l.Fset, pkgs)
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if len(diags) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,266 @@
+package godi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// --------
+//
+// Configuration loading applies a batch of name-based registrations, as
+// made by RegisterByName, from a document.  JSON is understood out of the
+// box; other formats can be plugged in with a ConfigDecoder.
+//
+// The JSON schema is:
+//
+//	{
+//	  "profiles": ["dev"],                 // optional, see below
+//	  "bindings": [
+//	    {
+//	      "target":      "safari.Animal",  // required, as for RegisterByName
+//	      "implementor": "safari.Hippo",   // required
+//	      "cached":      true,             // optional, default false
+//	      "name":        "big",            // optional registration name, see Named
+//	      "scope":       "root",           // optional, see below
+//	      "profile":     "test"            // optional, see below
+//	    }
+//	  ]
+//	}
+//
+// A binding with a profile is registered as usual if the profile is passed
+// to WithProfiles, and otherwise with InProfile, so that it is only used
+// while the profile is active.  The types of a binding for a profile that
+// isn't active when the configuration is loaded are checked when it is
+// resolved, so a configuration can name types only some builds have.
+//
+// "profiles" activates profiles for as long as the configuration is loaded,
+// see SetProfiles.  Profiles are process-wide, so this affects every scope,
+// not just the one the configuration is loaded into.
+//
+// --------
+
+// Config is a batch of bindings to register.
+type Config struct {
+	// Profiles are activated while the configuration is loaded, for every
+	// scope.
+	Profiles []string        `json:"profiles,omitempty"`
+	Bindings []ConfigBinding `json:"bindings"`
+}
+
+// ConfigBinding is a single name-based registration.
+type ConfigBinding struct {
+	Target      string `json:"target"`
+	Implementor string `json:"implementor"`
+	Cached      bool   `json:"cached,omitempty"`
+
+	// Name registers the binding with the Named option.
+	Name string `json:"name,omitempty"`
+
+	// Scope is the name of the scope to register in: the scope the config
+	// is loaded into, or one of its parents, e.g. "root" or a scope created
+	// with CreateNamedScope.  Empty means the scope the config is loaded
+	// into.
+	Scope string `json:"scope,omitempty"`
+
+	// Profile, if set, only uses the binding while the profile is active,
+	// or if it is passed to WithProfiles.
+	Profile string `json:"profile,omitempty"`
+}
+
+// ConfigDecoder decodes a configuration document.
+type ConfigDecoder interface {
+	Decode(r io.Reader, config *Config) error
+}
+
+// ConfigDecoderFunc adapts a function to a ConfigDecoder.
+type ConfigDecoderFunc func(r io.Reader, config *Config) error
+
+// Decode calls the function.
+func (p ConfigDecoderFunc) Decode(r io.Reader, config *Config) error {
+	return p(r, config)
+}
+
+// JSONDecoder decodes the JSON schema above, rejecting unknown fields.
+var JSONDecoder ConfigDecoder = ConfigDecoderFunc(func(r io.Reader, config *Config) error {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	return d.Decode(config)
+})
+
+var configDecoders = struct {
+	sync.RWMutex
+	byExt map[string]ConfigDecoder
+}{byExt: map[string]ConfigDecoder{".json": JSONDecoder}}
+
+// RegisterConfigDecoder sets the decoder LoadConfigFile uses for files with
+// the given extension, e.g. ".yaml".
+func RegisterConfigDecoder(ext string, decoder ConfigDecoder) {
+	configDecoders.Lock()
+	defer configDecoders.Unlock()
+	configDecoders.byExt[strings.ToLower(ext)] = decoder
+}
+
+// ConfigOption adjusts how a configuration is loaded.
+type ConfigOption func(*configLoader)
+
+type configLoader struct {
+	decoder  ConfigDecoder
+	profiles map[string]bool
+}
+
+// WithDecoder decodes the configuration with decoder instead of JSON, or
+// the decoder registered for the file's extension.
+func WithDecoder(decoder ConfigDecoder) ConfigOption {
+	return func(p *configLoader) {
+		p.decoder = decoder
+	}
+}
+
+// WithProfiles applies the bindings for profiles unconditionally.  Bindings
+// for other profiles are only used while their profile is active, and
+// bindings without a profile are always applied.
+func WithProfiles(profiles ...string) ConfigOption {
+	return func(p *configLoader) {
+		for _, profile := range profiles {
+			p.profiles[profile] = true
+		}
+	}
+}
+
+// configRegistrations unregisters a loaded configuration.
+type configRegistrations []Closable
+
+func (p configRegistrations) Close() {
+	for i := len(p) - 1; i >= 0; i-- {
+		p[i].Close()
+	}
+}
+
+// LoadConfig decodes a configuration from r and registers its bindings.
+// Every binding is checked first: its types must have been registered with
+// RegisterType, the implementor must implement the target, and its scope
+// must exist.  If any binding is invalid, nothing is registered and all of
+// the problems are returned.  Closing the result removes every binding.
+func (p *registrationContext) LoadConfig(r io.Reader, opts ...ConfigOption) (Closable, error) {
+	loader := &configLoader{decoder: JSONDecoder, profiles: map[string]bool{}}
+	for _, opt := range opts {
+		opt(loader)
+	}
+
+	var config Config
+	if err := loader.decoder.Decode(r, &config); err != nil {
+		return nil, fmt.Errorf("Error decoding config: %w", err)
+	}
+	return p.applyConfig(&config, loader)
+}
+
+// LoadConfigFile loads the configuration at path, see LoadConfig.  The
+// decoder is chosen by the file's extension, falling back to JSON.
+func (p *registrationContext) LoadConfigFile(path string, opts ...ConfigOption) (Closable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	configDecoders.RLock()
+	decoder := configDecoders.byExt[strings.ToLower(filepath.Ext(path))]
+	configDecoders.RUnlock()
+
+	if decoder != nil {
+		opts = append([]ConfigOption{WithDecoder(decoder)}, opts...)
+	}
+	return p.LoadConfig(f, opts...)
+}
+
+func (p *registrationContext) applyConfig(config *Config, loader *configLoader) (Closable, error) {
+	var errs []error
+	var scopes []*registrationContext
+	var bindings []ConfigBinding
+
+	// bindings for profiles that aren't active may name types that only
+	// exist in builds for those profiles, so their types are checked when
+	// they are resolved instead.
+	active := map[string]bool{}
+	for _, profile := range config.Profiles {
+		active[profile] = true
+	}
+	for profile := range loader.profiles {
+		active[profile] = true
+	}
+
+	for i, b := range config.Bindings {
+		checkTypes := b.Profile == "" || active[b.Profile] || ProfileActive(b.Profile)
+		scope, err := p.checkBinding(b, checkTypes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Binding %d (%s -> %s): %w", i, b.Target, b.Implementor, err))
+			continue
+		}
+		scopes = append(scopes, scope)
+		bindings = append(bindings, b)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	registrations := make(configRegistrations, 0, len(bindings)+1)
+	if len(config.Profiles) > 0 {
+		registrations = append(registrations, activateProfiles(config.Profiles))
+	}
+	for i, b := range bindings {
+		var opts []RegistrationOption
+		if b.Name != "" {
+			opts = append(opts, Named(b.Name))
+		}
+		if b.Profile != "" && !loader.profiles[b.Profile] {
+			opts = append(opts, InProfile(b.Profile))
+		}
+		registrations = append(registrations, scopes[i].RegisterByName(b.Target, b.Implementor, b.Cached, opts...))
+	}
+	return registrations, nil
+}
+
+// checkBinding validates b, returning the scope to register it in.  Its
+// types are only checked if checkTypes is set.
+func (p *registrationContext) checkBinding(b ConfigBinding, checkTypes bool) (*registrationContext, error) {
+	if b.Target == "" || b.Implementor == "" {
+		return nil, errors.New("Target and implementor are required")
+	}
+
+	scope := p
+	if b.Scope != "" {
+		for scope = p; scope != nil && scope.name != b.Scope; scope = scope.getParent() {
+		}
+		if scope == nil {
+			return nil, fmt.Errorf("No scope named '%s'", b.Scope)
+		}
+	}
+
+	if checkTypes {
+		if err := checkNamedTypes(b.Target, b.Implementor); err != nil {
+			return nil, err
+		}
+	}
+	return scope, nil
+}
+
+// checkNamedTypes checks that a name-based registration's types have been
+// registered with RegisterType, and that the implementor implements the
+// target.
+func checkNamedTypes(target string, implementor string) error {
+	t, err := newtypeInfo(target, nil).Type()
+	if err != nil {
+		return err
+	}
+	impl, err := newtypeInfo(implementor, nil).Type()
+	if err != nil {
+		return err
+	}
+	return (&typeRegistration{}).ensureImplementor(impl, t)
+}
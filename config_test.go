@@ -0,0 +1,158 @@
+package godi
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) registerConfigTypes() {
+	RegisterType((*I1)(nil))
+	RegisterType((*I2)(nil))
+	RegisterType(T1{})
+	RegisterType(T2{})
+}
+
+func (s *GoDiTestSuite) TestLoadConfig() {
+	s.registerConfigTypes()
+
+	closer, err := LoadConfig(strings.NewReader(`{
+		"bindings": [
+			{"target": "godi.I1", "implementor": "godi.T1", "cached": true},
+			{"target": "godi.I1", "implementor": "godi.T2", "name": "two"}
+		]
+	}`))
+	assert.Nil(s.T(), err)
+
+	r1, _ := Resolve((*I1)(nil))
+	r2, _ := Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r1)
+	assert.True(s.T(), r1 == r2)
+
+	named, err := ResolveNamed((*I1)(nil), "two")
+	assert.Nil(s.T(), err)
+	assert.IsType(s.T(), &T2{}, named)
+
+	closer.Close()
+	_, err = Resolve((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	_, err = ResolveNamed((*I1)(nil), "two")
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+}
+
+func (s *GoDiTestSuite) TestLoadConfigInvalid() {
+	s.registerConfigTypes()
+
+	_, err := LoadConfig(strings.NewReader(`{
+		"bindings": [
+			{"target": "godi.I1", "implementor": "godi.T1"},
+			{"target": "godi.I1", "implementor": "godi.Missing"},
+			{"target": "godi.I2", "implementor": "godi.T1"},
+			{"target": "godi.I1", "implementor": "godi.T2", "scope": "nowhere"}
+		]
+	}`))
+
+	var unknown *UnknownTypeError
+	var notImplemented *NotImplementedError
+	assert.True(s.T(), errors.As(err, &unknown))
+	assert.True(s.T(), errors.As(err, &notImplemented))
+	assert.True(s.T(), strings.Contains(err.Error(), "No scope named 'nowhere'"))
+
+	// nothing is registered if any binding is invalid
+	_, err = Resolve((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+
+	_, err = LoadConfig(strings.NewReader(`{"bindings": [{"target": "godi.I1", "implementor": "godi.T1", "lifetime": "cached"}]}`))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestLoadConfigScopesAndProfiles() {
+	s.registerConfigTypes()
+
+	scope := CreateScope(false)
+	defer scope.Close()
+
+	config := `{
+		"bindings": [
+			{"target": "godi.I1", "implementor": "godi.T1", "scope": "root"},
+			{"target": "godi.I1", "implementor": "godi.T2", "profile": "test"}
+		]
+	}`
+
+	closer, err := scope.LoadConfig(strings.NewReader(config))
+	assert.Nil(s.T(), err)
+	r, _ := scope.Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r)
+	closer.Close()
+
+	closer, err = scope.LoadConfig(strings.NewReader(config), WithProfiles("test"))
+	assert.Nil(s.T(), err)
+	defer closer.Close()
+
+	r, _ = scope.Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r)
+	r, _ = Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r)
+}
+
+func (s *GoDiTestSuite) TestLoadConfigNamedScope() {
+	s.registerConfigTypes()
+
+	tenant := CreateNamedScope("tenant", false)
+	defer tenant.Close()
+	request := tenant.CreateNamedScope("request")
+	defer request.Close()
+
+	config := `{
+		"bindings": [
+			{"target": "godi.I1", "implementor": "godi.T2", "scope": "tenant"}
+		]
+	}`
+
+	// loaded into the request scope, but registered in the tenant scope.
+	closer, err := request.LoadConfig(strings.NewReader(config))
+	assert.Nil(s.T(), err)
+	defer closer.Close()
+
+	r, _, err := tenant.ResolveWithTrace((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.IsType(s.T(), &T2{}, r)
+
+	_, trace, _ := request.ResolveWithTrace((*I1)(nil))
+	assert.Equal(s.T(), "tenant", trace.Scope)
+	assert.Equal(s.T(), []string{"request", "tenant"}, trace.ScopesSearched)
+
+	_, err = Resolve((*I1)(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestLoadConfigFileDecoder() {
+	s.registerConfigTypes()
+
+	// a toy line-based format: "target implementor"
+	RegisterConfigDecoder(".pairs", ConfigDecoderFunc(func(r io.Reader, config *Config) error {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			fields := strings.Fields(line)
+			config.Bindings = append(config.Bindings, ConfigBinding{Target: fields[0], Implementor: fields[1]})
+		}
+		return nil
+	}))
+
+	path := filepath.Join(s.T().TempDir(), "godi.pairs")
+	os.WriteFile(path, []byte("godi.I1 godi.T2\n"), 0644)
+
+	closer, err := LoadConfigFile(path)
+	assert.Nil(s.T(), err)
+	defer closer.Close()
+
+	r, _ := Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r)
+}
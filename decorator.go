@@ -0,0 +1,139 @@
+package godi
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// --------
+//
+// Decorators wrap resolved instances, e.g. with logging, metrics or caching
+// layers:
+//
+// func(inner Animal, log Logger) Animal
+//
+// The first parameter is the instance being decorated, and the rest are
+// resolved like provider parameters.  Every decorator for a target applies,
+// outermost scope first and in registration order within a scope, so a
+// child scope's decorators wrap its parents'.
+//
+// --------
+
+type decorator struct {
+	fn reflect.Value
+}
+
+// RegisterDecorator registers a function that wraps instances resolved for
+// target from this scope and its children.  fn takes the instance as
+// its first parameter, followed by any dependencies, and returns the
+// decorated instance, optionally with an error.
+//
+// Decorators are applied after an instance is created and initialized.
+// Cached instances, including those registered with
+// RegisterInstanceImplementor, are shared by every scope, so only the
+// decorators visible from the scope they were registered in apply, and the
+// decorated result is cached.  A decorator registered in a child scope
+// therefore never applies to its parents' cached registrations; register it
+// alongside them, or make them Scoped.  Closing the result removes the
+// decorator, but doesn't affect instances already decorated.
+func (p *registrationContext) RegisterDecorator(target interface{}, fn interface{}) (Closable, error) {
+	value, _, err := validateProvider(fn)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid decorator: %w", err)
+	}
+
+	t := instanceToType(target)
+	ft := value.Type()
+	if ft.NumIn() == 0 || !(ft.In(0) == t || t.Kind() != reflect.Interface && ft.In(0) == reflect.PtrTo(t)) {
+		return nil, fmt.Errorf("Decorator %v must take the %v being decorated as its first parameter", ft, t)
+	}
+	if err := ensureProvides(ft.Out(0), t); err != nil {
+		return nil, err
+	}
+
+	d := &decoratorToken{context: p, target: typeToString(t), decorator: &decorator{fn: value}}
+
+	p.rwlock.Lock()
+	defer p.rwlock.Unlock()
+	p.decorators[d.target] = append(p.decorators[d.target], d.decorator)
+	return d, nil
+}
+
+type decoratorToken struct {
+	context   *registrationContext
+	target    string
+	decorator *decorator
+}
+
+func (p *decoratorToken) Close() {
+	p.context.rwlock.Lock()
+	defer p.context.rwlock.Unlock()
+
+	decorators := p.context.decorators[p.target]
+	for i, d := range decorators {
+		if d == p.decorator {
+			p.context.decorators[p.target] = append(decorators[:i:i], decorators[i+1:]...)
+			return
+		}
+	}
+}
+
+// getDecorators returns a snapshot of this scope's decorators for typeName.
+func (p *registrationContext) getDecorators(typeName string) []*decorator {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+	return append([]*decorator(nil), p.decorators[typeName]...)
+}
+
+// decorate applies the decorators for reg's target visible from this scope,
+// outermost scope first.
+func (p *registrationContext) decorate(state *resolveState, reg *typeRegistration, instance interface{}) (interface{}, error) {
+	var chain []*registrationContext
+	for ctx := p; ctx != nil; ctx = ctx.getParent() {
+		chain = append(chain, ctx)
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, d := range chain[i].getDecorators(reg.targetType.typeName) {
+			start := time.Now()
+			decorated, err := p.callDecorator(state, d.fn, instance)
+			traceStep(state, fmt.Sprintf("decorator %v", d.fn.Type()), start, err)
+			if err != nil {
+				return nil, newInitError(reg, err)
+			}
+			instance = decorated
+		}
+	}
+	return instance, nil
+}
+
+// callDecorator passes instance to the decorator, resolving the rest of its
+// parameters from this scope.
+func (p *registrationContext) callDecorator(state *resolveState, fn reflect.Value, instance interface{}) (interface{}, error) {
+	ft := fn.Type()
+	args := make([]reflect.Value, ft.NumIn())
+
+	inner, err := assignableValue(instance, ft.In(0))
+	if err != nil {
+		return nil, fmt.Errorf("Decorator %v: %w", ft, err)
+	}
+	args[0] = inner
+
+	for i := 1; i < len(args); i++ {
+		arg, err := p.resolveValue(state, ft.In(i), "")
+		if err != nil {
+			return nil, fmt.Errorf("Resolving parameter %d (%v) of decorator %v: %w", i, ft.In(i), ft, err)
+		}
+		args[i] = arg
+	}
+
+	out := fn.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, fmt.Errorf("Decorator %v: %w", ft, out[1].Interface().(error))
+	}
+	if isNilValue(out[0]) {
+		return nil, fmt.Errorf("Decorator %v returned nil", ft)
+	}
+	return out[0].Interface(), nil
+}
@@ -0,0 +1,132 @@
+package godi
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TDecorated wraps an I1, appending its tag to F1.
+type TDecorated struct {
+	inner I1
+	tag   string
+}
+
+func (p *TDecorated) F1() string {
+	return p.inner.F1() + "+" + p.tag
+}
+
+func decorateWith(tag string) func(I1) I1 {
+	return func(inner I1) I1 {
+		return &TDecorated{inner: inner, tag: tag}
+	}
+}
+
+func (s *GoDiTestSuite) TestDecorator() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, func(instance interface{}) (bool, error) {
+		instance.(*T1).s = "t1"
+		return false, nil
+	})
+	RegisterDecorator((*I1)(nil), decorateWith("a"))
+	RegisterDecorator((*I1)(nil), decorateWith("b"))
+
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "t1+a+b", r.(I1).F1())
+
+	// child scope decorators layer on top of the parent's
+	scope := CreateScope(false)
+	defer scope.Close()
+	closer, _ := scope.RegisterDecorator((*I1)(nil), decorateWith("c"))
+
+	r, _ = scope.Resolve((*I1)(nil))
+	assert.Equal(s.T(), "t1+a+b+c", r.(I1).F1())
+
+	closer.Close()
+	r, _ = scope.Resolve((*I1)(nil))
+	assert.Equal(s.T(), "t1+a+b", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestDecoratorCached() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "instance"})
+	RegisterTypeImplementor(T2{}, T2{}, true, nil)
+
+	calls := 0
+	RegisterDecorator((*I1)(nil), func(inner I1, dep T2) (I1, error) {
+		calls++
+		return &TDecorated{inner: inner, tag: "decorated"}, nil
+	})
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterDecorator((*I1)(nil), decorateWith("scope"))
+
+	r1, _ := scope.Resolve((*I1)(nil))
+	r2, _ := Resolve((*I1)(nil))
+	assert.Equal(s.T(), "instance+decorated", r1.(I1).F1())
+	assert.True(s.T(), r1 == r2)
+	assert.Equal(s.T(), 1, calls)
+}
+
+func (s *GoDiTestSuite) TestDecoratorChildScopeCached() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, true, nil)
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil, Named("transient"))
+	RegisterDecorator((*I1)(nil), decorateWith("root"))
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterDecorator((*I1)(nil), decorateWith("scope"))
+
+	// the cached root registration only gets root's decorators, even when
+	// first resolved from the child
+	r1, err := scope.Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "+root", r1.(I1).F1())
+	r2, _ := Resolve((*I1)(nil))
+	assert.True(s.T(), r1 == r2)
+
+	// a transient one is decorated by the resolving scope's decorators
+	r, err := scope.ResolveNamed((*I1)(nil), "transient")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "+root+scope", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestDecoratorScoped() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil, Scoped())
+	RegisterDecorator((*I1)(nil), decorateWith("root"))
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterDecorator((*I1)(nil), decorateWith("scope"))
+
+	r1, _ := scope.Resolve((*I1)(nil))
+	r2, _ := scope.Resolve((*I1)(nil))
+	assert.Equal(s.T(), "+root+scope", r1.(I1).F1())
+	assert.True(s.T(), r1 == r2)
+}
+
+func (s *GoDiTestSuite) TestDecoratorErrors() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	_, err := RegisterDecorator((*I1)(nil), func(inner I2) I1 { return nil })
+	assert.NotNil(s.T(), err)
+	_, err = RegisterDecorator((*I1)(nil), func(inner I1) {})
+	assert.NotNil(s.T(), err)
+	_, err = RegisterDecorator((*I1)(nil), func(inner I1) I2 { return nil })
+	assert.NotNil(s.T(), err)
+
+	fail := errors.New("fail")
+	RegisterDecorator((*I1)(nil), func(inner I1) (I1, error) { return nil, fail })
+	_, err = Resolve((*I1)(nil))
+	var initErr *InitError
+	assert.True(s.T(), errors.As(err, &initErr))
+	assert.True(s.T(), errors.Is(err, fail))
+
+	Reset()
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	RegisterDecorator((*I1)(nil), func(inner I1, missing I2) I1 { return inner })
+	_, err = Resolve((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	assert.True(s.T(), strings.Contains(err.Error(), "decorator"))
+}
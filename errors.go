@@ -0,0 +1,123 @@
+package godi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// --------
+//
+// Error types returned by godi.  All of them can be inspected with
+// errors.Is and errors.As.
+//
+// --------
+
+// ErrNotFound is the sentinel for a missing registration.  Resolve returns a
+// *NotFoundError, which matches ErrNotFound with errors.Is.
+var ErrNotFound = errors.New("NotFound")
+
+// NotFoundError is returned when no scope in the chain has a registration
+// for the requested type.
+type NotFoundError struct {
+	TypeName string
+
+	// Name is the registration name requested, if any.
+	Name string
+
+	// Scopes lists the names of the scopes searched, innermost first.
+	Scopes []string
+
+	// Inactive lists the registrations that were skipped because their
+	// profiles or conditions don't hold, as "scope: implementor (condition)",
+	// and Profiles the profiles that were active.  They are only set by
+	// Resolve and friends.
+	Inactive []string
+	Profiles []string
+}
+
+func (p *NotFoundError) Error() string {
+	name := p.TypeName
+	if p.Name != "" {
+		name += " named " + p.Name
+	}
+	msg := fmt.Sprintf("%v: no registration for '%s' (searched %s)", ErrNotFound, name, strings.Join(p.Scopes, ", "))
+	if len(p.Inactive) > 0 {
+		profiles := "none"
+		if len(p.Profiles) > 0 {
+			profiles = strings.Join(p.Profiles, ", ")
+		}
+		msg += fmt.Sprintf("; skipped inactive %s; active profiles: %s", strings.Join(p.Inactive, ", "), profiles)
+	}
+	return msg
+}
+
+func (p *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+// NotImplementedError is returned when registering an implementor that does
+// not satisfy the target.
+type NotImplementedError struct {
+	Implementor string
+	Target      string
+}
+
+func (p *NotImplementedError) Error() string {
+	return fmt.Sprintf("Expected %s to implement %s", p.Implementor, p.Target)
+}
+
+// InitError is returned when creating or initializing an instance fails,
+// either in a provider, an InitializeCallback, GodiInit or an
+// InstanceInitializer.  Err is the underlying error.
+type InitError struct {
+	TypeName string
+	Target   string
+	Err      error
+}
+
+func (p *InitError) Error() string {
+	return fmt.Sprintf("Error initializing '%s' (registered for target '%s'): %v", p.TypeName, p.Target, p.Err)
+}
+
+func (p *InitError) Unwrap() error {
+	return p.Err
+}
+
+// UnknownTypeError is returned when a name-based registration refers to a
+// type that hasn't been registered with RegisterType.
+type UnknownTypeError struct {
+	TypeName string
+}
+
+func (p *UnknownTypeError) Error() string {
+	return fmt.Sprintf("Can't find type '%s', did you forget to register it?", p.TypeName)
+}
+
+// MissingTargetError is returned by Install when a target a module
+// requires has no registration before the module is installed, or one it
+// provides has none once it is.
+// It matches ErrNotFound with errors.Is.
+type MissingTargetError struct {
+	Module   string
+	TypeName string
+
+	// Provided is true if the module declared it provides the target, and
+	// false if it requires it.
+	Provided bool
+}
+
+func (p *MissingTargetError) Error() string {
+	if p.Provided {
+		return fmt.Sprintf("Module '%s' provides '%s', but doesn't register it", p.Module, p.TypeName)
+	}
+	return fmt.Sprintf("Module '%s' requires '%s', which is not registered", p.Module, p.TypeName)
+}
+
+func (p *MissingTargetError) Unwrap() error {
+	return ErrNotFound
+}
+
+func newInitError(reg *typeRegistration, err error) error {
+	return &InitError{TypeName: reg.implType.typeName, Target: reg.targetType.typeName, Err: err}
+}
@@ -17,7 +17,7 @@ type FBInjectInstanceInitializer struct {
 	initializers map[string]*InitItem
 }
 
-var _ godi.InstanceInitializer = FBInjectInstanceInitializer{}
+var _ godi.ScopeInitializer = FBInjectInstanceInitializer{}
 
 func NewFBInjectInstanceInitializer() *FBInjectInstanceInitializer {
 	return &FBInjectInstanceInitializer{
@@ -35,7 +35,18 @@ func (p FBInjectInstanceInitializer) CanInitialize(instance interface{}, typeNam
 	return p.initializers[typeName] != nil
 }
 
+// Initialize resolves the dependencies from the current global scope.
 func (p FBInjectInstanceInitializer) Initialize(instance interface{}, typeName string) (interface{}, error) {
+	return p.initialize(godi.Resolve, instance, typeName)
+}
+
+// InitializeInScope resolves the dependencies from the scope the instance
+// is being resolved against, so cycles through them are detected.
+func (p FBInjectInstanceInitializer) InitializeInScope(scope godi.RegistrationContext, instance interface{}, typeName string) (interface{}, error) {
+	return p.initialize(scope.Resolve, instance, typeName)
+}
+
+func (p FBInjectInstanceInitializer) initialize(resolve func(interface{}) (interface{}, error), instance interface{}, typeName string) (interface{}, error) {
 
 	var g inject.Graph
 
@@ -45,7 +56,7 @@ func (p FBInjectInstanceInitializer) Initialize(instance interface{}, typeName s
 
 	if entry := p.initializers[typeName]; entry != nil {
 		for _, v := range entry.dependencies {
-			resolved, e1 := godi.Resolve(v)
+			resolved, e1 := resolve(v)
 			if e1 != nil {
 				return nil, e1
 			}
@@ -1,6 +1,7 @@
 package fbinject
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/shawnburke/godi"
@@ -56,3 +57,34 @@ func TestFbInject(t *testing.T) {
 	}
 
 }
+
+type TD2Cycle struct {
+	Dep I1 `inject:""`
+}
+
+func (p TD2Cycle) Check() string {
+	return p.Dep.CheckCheck()
+}
+
+func TestFbInjectCycle(t *testing.T) {
+	godi.Reset()
+	defer godi.Reset()
+
+	var inject = NewFBInjectInstanceInitializer()
+	inject.AddInitializer(T1{}, []interface{}{(*D2)(nil)})
+	inject.AddInitializer(TD2Cycle{}, []interface{}{(*I1)(nil)})
+	godi.RegisterInstanceInitializer(inject)
+
+	godi.RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	godi.RegisterTypeImplementor((*D2)(nil), TD2Cycle{}, false, nil)
+
+	_, err := godi.Resolve((*I1)(nil))
+
+	var cycle *godi.CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Expected a cycle error, got %v", err)
+	}
+	if len(cycle.Path) != 3 {
+		t.Errorf("Unexpected cycle path %v", cycle.Path)
+	}
+}
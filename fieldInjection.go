@@ -0,0 +1,158 @@
+package godi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// --------
+//
+// Field injection populates struct fields tagged `godi:""` on instances
+// godi creates.  The tag value is a comma-separated list of options:
+//
+// name=<name>  resolve a named registration
+// optional     leave the field as its zero value if nothing is registered
+// value=<key>  set the field from a configuration value instead, see values.go
+// default=<v>  the value to use if the key isn't set; takes the rest of the tag
+//
+// The set of fields to inject is computed once per type and cached.
+//
+// --------
+
+const injectTag = "godi"
+
+type fieldInjection struct {
+	index     int
+	fieldName string
+	fieldType reflect.Type
+	exported  bool
+	name      string
+	optional  bool
+
+	// value is the configuration key for value fields, which are set by
+	// injectValues rather than resolved.
+	value        string
+	defaultValue string
+	hasDefault   bool
+}
+
+type fieldPlan struct {
+	fields    []fieldInjection
+	hasValues bool
+	err       error
+}
+
+var fieldPlans sync.Map
+
+// planFields returns the cached injection plan for struct type t.
+func planFields(t reflect.Type) *fieldPlan {
+	if plan, ok := fieldPlans.Load(t); ok {
+		return plan.(*fieldPlan)
+	}
+
+	plan := &fieldPlan{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup(injectTag)
+		if !ok {
+			continue
+		}
+
+		fi := fieldInjection{
+			index:     i,
+			fieldName: f.Name,
+			fieldType: f.Type,
+			exported:  f.PkgPath == "",
+		}
+		if err := fi.parseTag(tag); err != nil {
+			plan.err = fmt.Errorf("Invalid godi tag on %v.%s: %w", t, f.Name, err)
+			break
+		}
+		plan.fields = append(plan.fields, fi)
+		plan.hasValues = plan.hasValues || fi.value != ""
+	}
+
+	actual, _ := fieldPlans.LoadOrStore(t, plan)
+	return actual.(*fieldPlan)
+}
+
+func (p *fieldInjection) parseTag(tag string) error {
+	// default= takes the rest of the tag, so defaults can contain commas.
+	if i := strings.Index(","+tag, ",default="); i >= 0 {
+		p.defaultValue = tag[i+len("default="):]
+		p.hasDefault = true
+		tag = tag[:i]
+	}
+
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "":
+		case opt == "optional":
+			p.optional = true
+		case strings.HasPrefix(opt, "name="):
+			p.name = strings.TrimPrefix(opt, "name=")
+		case strings.HasPrefix(opt, "value="):
+			p.value = strings.TrimPrefix(opt, "value=")
+		default:
+			return fmt.Errorf("unknown option '%s'", opt)
+		}
+	}
+
+	if p.hasDefault && p.value == "" {
+		return fmt.Errorf("default requires value=<key>")
+	}
+	return nil
+}
+
+// injectFields resolves the tagged fields of instance from this scope.  Only
+// pointers to structs are injected, and fields that are already set are
+// left alone.  constructed is true if godi created the instance itself, see
+// injectableField.
+func (p *registrationContext) injectFields(state *resolveState, instance interface{}, constructed bool) error {
+	v := reflect.ValueOf(instance)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+
+	plan := planFields(v.Type())
+	if plan.err != nil {
+		return plan.err
+	}
+
+	for _, fi := range plan.fields {
+		field, ok := injectableField(v, fi, constructed)
+		if !ok || fi.value != "" || !field.IsZero() {
+			continue
+		}
+
+		val, err := p.resolveValue(state, fi.fieldType, fi.name)
+		if err != nil {
+			if fi.optional && isMissing(err, fi.fieldType, fi.name) {
+				continue
+			}
+			return fmt.Errorf("Injecting field %v.%s: %w", v.Type(), fi.fieldName, err)
+		}
+		field.Set(val)
+	}
+	return nil
+}
+
+// injectableField returns the field of struct v that fi describes, in a form
+// that can be set, or false if godi shouldn't set it.  Unexported fields are
+// only set on instances godi constructed, which nothing else has seen yet;
+// those returned by providers or replaced by hooks are left alone.
+func injectableField(v reflect.Value, fi fieldInjection, constructed bool) (reflect.Value, bool) {
+	field := v.Field(fi.index)
+	if fi.exported {
+		return field, true
+	}
+	if !constructed {
+		return reflect.Value{}, false
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem(), true
+}
@@ -0,0 +1,82 @@
+package godi
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	TInjected struct {
+		Dep      I1 `godi:""`
+		dep      I1 `godi:""`
+		Missing  I2 `godi:"optional"`
+		Ignored  I1
+		Concrete *T3 `godi:""`
+	}
+	TBadTag struct {
+		Dep I1 `godi:"bogus"`
+	}
+	TMissing struct {
+		Dep I2 `godi:""`
+	}
+)
+
+func (p *TInjected) F3() string {
+	return p.Dep.F1() + p.dep.F1()
+}
+
+func (s *GoDiTestSuite) TestFieldInjection() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "a"})
+	RegisterTypeImplementor(T3{}, T3{}, false, nil)
+	RegisterTypeImplementor((*I3)(nil), TInjected{}, false, nil)
+
+	r, err := Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+
+	inst := r.(*TInjected)
+	assert.Equal(s.T(), "aa", inst.F3())
+	assert.Nil(s.T(), inst.Missing)
+	assert.Nil(s.T(), inst.Ignored)
+	assert.Equal(s.T(), 42, inst.Concrete.n)
+}
+
+func (s *GoDiTestSuite) TestFieldInjectionScope() {
+	RegisterTypeImplementor((*I3)(nil), TInjected{}, false, nil)
+	RegisterTypeImplementor(T3{}, T3{}, false, nil)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "b"})
+
+	r, err := scope.Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "bb", r.(I3).F3())
+}
+
+func (s *GoDiTestSuite) TestFieldInjectionProvider() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "a"})
+	RegisterTypeImplementor(T3{}, T3{}, false, nil)
+
+	// fields the provider sets are left alone, and unexported fields of
+	// instances godi didn't construct aren't injected.
+	RegisterProvider((*I3)(nil), func() *TInjected {
+		return &TInjected{Dep: T1{s: "p"}}
+	}, false)
+
+	r, err := Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+
+	inst := r.(*TInjected)
+	assert.Equal(s.T(), "p", inst.Dep.F1())
+	assert.Nil(s.T(), inst.dep)
+	assert.NotNil(s.T(), inst.Concrete)
+}
+
+func (s *GoDiTestSuite) TestFieldInjectionFail() {
+	RegisterTypeImplementor(TMissing{}, TMissing{}, false, nil)
+	_, err := Resolve(TMissing{})
+	assert.NotNil(s.T(), err)
+
+	RegisterTypeImplementor(TBadTag{}, TBadTag{}, false, nil)
+	_, err = Resolve(TBadTag{})
+	assert.NotNil(s.T(), err)
+}
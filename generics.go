@@ -0,0 +1,123 @@
+package godi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// --------
+//
+// Generic, type-safe wrappers over the interface{} API.
+//
+// These helpers take the target as a type parameter rather than a
+// (*Interface)(nil) value and return typed results, so callers no longer
+// need to type-assert.  They operate on any RegistrationContext and
+// interoperate with registrations made through the interface{} API.
+//
+// --------
+
+// typeOf returns the reflect.Type for a type parameter, including interfaces.
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// contextOrCurrent returns ctx, or the current global scope if ctx is nil.
+func contextOrCurrent(ctx RegistrationContext) RegistrationContext {
+	if ctx == nil {
+		return currentContext()
+	}
+	return ctx
+}
+
+// convertTo converts a resolved value to T.  Type registrations are created as
+// pointers, so a *T is dereferenced when T itself is requested.
+func convertTo[T any](raw interface{}) (T, error) {
+	var zero T
+	switch v := raw.(type) {
+	case T:
+		return v, nil
+	case *T:
+		if v != nil {
+			return *v, nil
+		}
+	}
+	return zero, fmt.Errorf("Resolved %T, which is not assignable to %v", raw, typeOf[T]())
+}
+
+// ResolveAs returns an instance of T from ctx.  If ctx is nil, the current
+// global scope is used.
+//
+// Example:
+//
+// animal, err := godi.ResolveAs[Animal](scope)
+func ResolveAs[T any](ctx RegistrationContext) (T, error) {
+	raw, err := contextOrCurrent(ctx).Resolve((*T)(nil))
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convertTo[T](raw)
+}
+
+// ResolveNamedAs returns the implementor of T registered with the given name
+// in ctx.  If ctx is nil, the current global scope is used.
+func ResolveNamedAs[T any](ctx RegistrationContext, name string) (T, error) {
+	raw, err := contextOrCurrent(ctx).ResolveNamed((*T)(nil), name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convertTo[T](raw)
+}
+
+// MustResolveAs is like ResolveAs but panics if T can't be resolved.  It is
+// intended for program setup, where a missing registration is fatal.
+func MustResolveAs[T any](ctx RegistrationContext) T {
+	v, err := ResolveAs[T](ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// RegisterTypeAs registers Impl as the implementor of T in ctx.  If ctx is nil,
+// the current global scope is used.
+//
+// Go can't express "Impl implements T" as a constraint when T is itself a type
+// parameter, so this is checked when the registration is made.  Use
+// RegisterInstanceAs for a compile-time checked registration.
+//
+// Example:
+//
+// godi.RegisterTypeAs[Animal, *Hippo](scope, false, nil)
+func RegisterTypeAs[T any, Impl any](ctx RegistrationContext, cached bool, init InitializeCallback, opts ...RegistrationOption) (Closable, error) {
+	impl := typeOf[Impl]()
+	if impl.Kind() == reflect.Ptr {
+		impl = impl.Elem()
+	}
+	return contextOrCurrent(ctx).RegisterTypeImplementor((*T)(nil), impl, cached, init, opts...)
+}
+
+// RegisterInstanceAs registers instance as the implementor of T in ctx.  If
+// ctx is nil, the current global scope is used.  Since instance is typed as T,
+// the compiler verifies it satisfies T.
+func RegisterInstanceAs[T any](ctx RegistrationContext, instance T, opts ...RegistrationOption) (Closable, error) {
+	return contextOrCurrent(ctx).RegisterInstanceImplementor((*T)(nil), instance, opts...)
+}
+
+// ResolveAllAs returns every implementor of T registered in ctx and its
+// parents.  If ctx is nil, the current global scope is used.
+func ResolveAllAs[T any](ctx RegistrationContext) ([]T, error) {
+	raw, err := contextOrCurrent(ctx).ResolveAll((*T)(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]T, len(raw))
+	for i, r := range raw {
+		if all[i], err = convertTo[T](r); err != nil {
+			return nil, err
+		}
+	}
+	return all, nil
+}
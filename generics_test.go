@@ -0,0 +1,76 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestResolveAs() {
+	res, err := RegisterTypeAs[I1, T2](nil, false, nil)
+	assert.Nil(s.T(), err)
+
+	i1, err := ResolveAs[I1](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "t2", i1.F1())
+
+	res.Close()
+
+	_, err = ResolveAs[I1](nil)
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestResolveAsPointerImpl() {
+	RegisterTypeAs[I1, *T3](nil, true, nil)
+
+	i1 := MustResolveAs[I1](nil)
+	assert.Equal(s.T(), "42", i1.F1())
+
+	// the concrete type can be requested as a value or a pointer
+	RegisterTypeAs[T3, T3](nil, false, nil)
+
+	t3 := MustResolveAs[T3](nil)
+	assert.Equal(s.T(), 42, t3.n)
+
+	p3 := MustResolveAs[*T3](nil)
+	assert.Equal(s.T(), 42, p3.n)
+}
+
+func (s *GoDiTestSuite) TestResolveAsScope() {
+	scope := CreateScope(false)
+	defer scope.Close()
+
+	RegisterInstanceAs[I1](scope, T1{s: "scoped"})
+
+	i1, err := ResolveAs[I1](scope)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "scoped", i1.F1())
+
+	// registered through the generic API, visible to the interface{} API
+	raw, err := scope.Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "scoped", raw.(I1).F1())
+
+	_, err = ResolveAs[I1](nil)
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestResolveAsWrongType() {
+	// name-based registrations aren't checked until resolve
+	RegisterType(T1{})
+	RegisterByName("godi.T2", "godi.T1", false)
+
+	_, err := ResolveAs[T2](nil)
+	assert.NotNil(s.T(), err)
+
+	assert.Panics(s.T(), func() { MustResolveAs[I2](nil) })
+}
+
+func (s *GoDiTestSuite) TestMustResolveAsPanicsWithError() {
+	defer func() {
+		err, ok := recover().(error)
+		assert.True(s.T(), ok)
+		assert.True(s.T(), errors.Is(err, ErrNotFound))
+	}()
+	MustResolveAs[I2](nil)
+}
@@ -27,29 +27,40 @@
 package godi
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
-)
-
-//
-// Error strings
-//
-const (
-	ErrorRegistrationNotFound = "NotFound"
+	"sync"
 )
 
 //
 // Global State and helpers
 //
 var typeMap = make(map[string]*reflect.Type)
-var registrationCounter int
+var typeMapLock sync.RWMutex
+var registrationCounter int64
 var rootContext = newregistrationContext(nil)
-var currentContext = rootContext
 
+// getRegisteredTypes returns a snapshot of the registered types.
 func getRegisteredTypes() *map[string]*reflect.Type {
-	return &typeMap
+	typeMapLock.RLock()
+	defer typeMapLock.RUnlock()
+
+	types := make(map[string]*reflect.Type, len(typeMap))
+	for k, v := range typeMap {
+		types[k] = v
+	}
+	return &types
+}
+
+// lookupType returns the type registered with RegisterType under name, or nil.
+func lookupType(name string) *reflect.Type {
+	typeMapLock.RLock()
+	defer typeMapLock.RUnlock()
+	return typeMap[name]
 }
 
 // ExtractType is a helper method that returns the reflect.Type and [package].[type] name
@@ -79,9 +90,14 @@ func typeToString(t reflect.Type) string {
 
 // Reset all the things.
 func Reset() {
+	typeMapLock.Lock()
 	typeMap = make(map[string]*reflect.Type)
+	typeMapLock.Unlock()
+
 	rootContext.Reset()
-	currentContext = rootContext
+	resetPushedScopes()
+	resetOverrides()
+	resetProfiles()
 }
 
 //
@@ -106,11 +122,28 @@ type Initializable interface {
 // them
 type RegistrationContext interface {
 	Closable
-	RegisterByName(target string, implementor string, cached bool) Closable
-	RegisterInstanceImplementor(target interface{}, instance interface{}) (Closable, error)
-	RegisterTypeImplementor(target interface{}, implementorType interface{}, cached bool, init InitializeCallback) (Closable, error)
+	RegisterByName(target string, implementor string, cached bool, opts ...RegistrationOption) Closable
+	RegisterInstanceImplementor(target interface{}, instance interface{}, opts ...RegistrationOption) (Closable, error)
+	RegisterTypeImplementor(target interface{}, implementorType interface{}, cached bool, init InitializeCallback, opts ...RegistrationOption) (Closable, error)
+	RegisterProvider(target interface{}, provider interface{}, cached bool, opts ...RegistrationOption) (Closable, error)
+	RegisterDefault(target interface{}, implementorType interface{}, cached bool, opts ...RegistrationOption) (Closable, error)
+	RegisterDecorator(target interface{}, decorator interface{}) (Closable, error)
+	RegisterHook(phase HookPhase, hook ResolveHook) Closable
+	AddValueSource(source ValueSource) Closable
+	Install(module *Module) (Closable, error)
+	LoadConfig(r io.Reader, opts ...ConfigOption) (Closable, error)
+	LoadConfigFile(path string, opts ...ConfigOption) (Closable, error)
 	Resolve(target interface{}) (interface{}, error)
+	TryResolve(target interface{}) (interface{}, error)
+	ResolveNamed(target interface{}, name string) (interface{}, error)
+	ResolveAll(target interface{}) ([]interface{}, error)
+	ResolveWithTrace(target interface{}) (interface{}, *ResolveTrace, error)
 	CreateScope() RegistrationContext
+	CreateNamedScope(name string) RegistrationContext
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Verify(construct bool) error
+	Graph() *Graph
 	Reset()
 }
 
@@ -121,18 +154,31 @@ type InstanceInitializer interface {
 	Initialize(instance interface{}, typeName string) (interface{}, error)
 }
 
+// ScopeInitializer is an InstanceInitializer that resolves what it needs
+// from the scope the instance is being resolved against, which is passed to
+// InitializeInScope instead of calling Initialize.  Resolves made through
+// that scope are part of the resolve in progress, so a dependency cycle
+// through the initializer is reported as a *CycleError, even if it resolves
+// on another goroutine while the instance is being created.
+type ScopeInitializer interface {
+	InstanceInitializer
+	InitializeInScope(scope RegistrationContext, instance interface{}, typeName string) (interface{}, error)
+}
+
 // RegistrationToken allows removal of a registration by the caller
 type RegistrationToken struct {
 	context      *registrationContext
 	registration *typeRegistration
+	once         sync.Once
 }
 
-// Close removes a registration from it's parent scope.
+// Close removes a registration from it's parent scope, and stops any instance
+// it created.  It is safe to call more than once, from any goroutine.
 func (p *RegistrationToken) Close() {
-	if p.context != nil {
+	p.once.Do(func() {
 		p.context.removeRegistration(p.registration)
-		p.context = nil
-	}
+		p.context.stopRegistration(context.Background(), p.registration)
+	})
 }
 
 // RegisterType registers a type with the DI framework.  This is required for using the type downstream, and generally
@@ -154,6 +200,9 @@ func RegisterType(val interface{}) error {
 
 	t, name := ExtractType(val)
 
+	typeMapLock.Lock()
+	defer typeMapLock.Unlock()
+
 	if typeMap[name] != nil {
 		return errors.New("Already registered: " + name)
 	}
@@ -165,7 +214,7 @@ func RegisterType(val interface{}) error {
 // RegisterInstanceInitializer registers an object that will be invoked when a new object is created
 // by the DI system.  See the InstanceInitializer interface.
 func RegisterInstanceInitializer(initializer InstanceInitializer) error {
-	return currentContext.RegisterInstanceInitializer(initializer)
+	return currentContext().RegisterInstanceInitializer(initializer)
 }
 
 func instanceToType(instance interface{}) reflect.Type {
@@ -176,8 +225,8 @@ func instanceToType(instance interface{}) reflect.Type {
 // RegisterInstanceImplementor registers an instance as the implementor of
 // an interface for this scope
 // -target The target interface
-func RegisterInstanceImplementor(target interface{}, instance interface{}) (Closable, error) {
-	return currentContext.RegisterInstanceImplementor(target, instance)
+func RegisterInstanceImplementor(target interface{}, instance interface{}, opts ...RegistrationOption) (Closable, error) {
+	return currentContext().RegisterInstanceImplementor(target, instance, opts...)
 }
 
 // RegisterTypeImplementor registers a type as the implementor of an interface for this scope
@@ -185,8 +234,17 @@ func RegisterInstanceImplementor(target interface{}, instance interface{}) (Clos
 // -implementorType The implementing type
 // -cached Set true to return the same instance for subsequent calls, false to create a new one each time
 // -init A callback to be called to initialize the object.
-func RegisterTypeImplementor(target interface{}, implementorType interface{}, cached bool, init InitializeCallback) (Closable, error) {
-	return currentContext.RegisterTypeImplementor(target, implementorType, cached, init)
+func RegisterTypeImplementor(target interface{}, implementorType interface{}, cached bool, init InitializeCallback, opts ...RegistrationOption) (Closable, error) {
+	return currentContext().RegisterTypeImplementor(target, implementorType, cached, init, opts...)
+}
+
+// RegisterProvider registers a function that creates the implementor of an interface for this scope.
+// The function's parameters are resolved from the scope being resolved against before it is called.
+// -target The target interface
+// -provider A function returning the implementor, or the implementor and an error
+// -cached Set true to return the same instance for subsequent calls, false to call the provider each time
+func RegisterProvider(target interface{}, provider interface{}, cached bool, opts ...RegistrationOption) (Closable, error) {
+	return currentContext().RegisterProvider(target, provider, cached, opts...)
 }
 
 // RegisterByName allow registration of targets and implmentors by name.  When the
@@ -194,41 +252,159 @@ func RegisterTypeImplementor(target interface{}, implementorType interface{}, ca
 // -target The target interface
 // -implementor The implementing type
 // -cached If true, returns the same instance for each type.
-func RegisterByName(target string, implementor string, cached bool) Closable {
-	return currentContext.RegisterByName(target, implementor, cached)
+func RegisterByName(target string, implementor string, cached bool, opts ...RegistrationOption) Closable {
+	return currentContext().RegisterByName(target, implementor, cached, opts...)
+}
+
+// RegisterDefault registers a type as the default implementor of an interface for this scope.  It is
+// only used when no scope has a regular registration for the interface, so applications can
+// override it.
+// -target The target interface
+// -implementorType The implementing type
+// -cached Set true to return the same instance for subsequent calls, false to create a new one each time
+func RegisterDefault(target interface{}, implementorType interface{}, cached bool, opts ...RegistrationOption) (Closable, error) {
+	return currentContext().RegisterDefault(target, implementorType, cached, opts...)
+}
+
+// RegisterDecorator registers a function that wraps instances resolved for the target from this
+// scope and its children.  See RegistrationContext.RegisterDecorator.
+// -target The target interface
+// -decorator A function taking the instance as its first parameter, followed
+// by any dependencies, e.g. func(inner Animal, log Logger) Animal
+func RegisterDecorator(target interface{}, decorator interface{}) (Closable, error) {
+	return currentContext().RegisterDecorator(target, decorator)
+}
+
+// RegisterHook registers a hook to run in a phase of resolves against the current scope and its
+// children.  See RegistrationContext.RegisterHook.
+// -phase The point in the resolve pipeline to run at
+// -hook A function that can inspect the resolve, veto it by returning an error, or replace its result
+func RegisterHook(phase HookPhase, hook ResolveHook) Closable {
+	return currentContext().RegisterHook(phase, hook)
+}
+
+// AddValueSource adds a source of values for fields tagged `godi:"value=<key>"` to the current
+// scope.  See RegistrationContext.AddValueSource.
+func AddValueSource(source ValueSource) Closable {
+	return currentContext().AddValueSource(source)
+}
+
+// Install makes the registrations of a module and its sub-modules in the current scope.  See
+// RegistrationContext.Install.
+func Install(module *Module) (Closable, error) {
+	return currentContext().Install(module)
+}
+
+// LoadConfig registers the bindings in a configuration document with the
+// current scope.  See RegistrationContext.LoadConfig.
+func LoadConfig(r io.Reader, opts ...ConfigOption) (Closable, error) {
+	return currentContext().LoadConfig(r, opts...)
+}
+
+// LoadConfigFile registers the bindings in a configuration file with the
+// current scope.  See RegistrationContext.LoadConfigFile.
+func LoadConfigFile(path string, opts ...ConfigOption) (Closable, error) {
+	return currentContext().LoadConfigFile(path, opts...)
 }
 
 // Resolve returns an instance of the requested interface, or an error
 // -target The targetType
 func Resolve(instance interface{}) (interface{}, error) {
-	return currentContext.Resolve(instance)
+	return currentContext().Resolve(instance)
+}
+
+// TryResolve is like Resolve, but returns nil rather than an error if nothing is registered for
+// the target.
+func TryResolve(target interface{}) (interface{}, error) {
+	return currentContext().TryResolve(target)
+}
+
+// ResolveNamed returns an instance of the implementor registered for the target
+// with the given name.  See the Named registration option.
+func ResolveNamed(target interface{}, name string) (interface{}, error) {
+	return currentContext().ResolveNamed(target, name)
+}
+
+// ResolveAll returns every implementor registered for the target in the current
+// scope and its parents.  See RegistrationContext.ResolveAll.
+func ResolveAll(target interface{}) ([]interface{}, error) {
+	return currentContext().ResolveAll(target)
+}
+
+// ResolveWithTrace resolves the target like Resolve, and also returns a trace
+// of how it was resolved.  See SetTraceHandler to trace every resolution.
+func ResolveWithTrace(target interface{}) (interface{}, *ResolveTrace, error) {
+	return currentContext().ResolveWithTrace(target)
 }
 
 // ResolveByName returns an instance of the requested interface, by name, like
 // package.Type (e.g. myPackage.MyInterface)
 func ResolveByName(target string) (interface{}, error) {
-	reg := currentContext.findRegistration(target)
+	reg := currentContext().findRegistration(target, "", false)
+	if reg == nil {
+		reg = currentContext().findRegistration(target, "", true)
+	}
 	if reg == nil {
-		return nil, errors.New(ErrorRegistrationNotFound)
+		return nil, &NotFoundError{TypeName: formatType(target), Scopes: []string{currentContext().name}}
+	}
+	t, err := reg.targetType.Type()
+	if err != nil {
+		return nil, err
 	}
-	return currentContext.resolveCore(*reg.targetType.reflectType)
+	return currentContext().resolveCore(nil, t, "")
+}
+
+// Start starts the instances managed by the current scope.  See
+// RegistrationContext.Start.
+func Start(ctx context.Context) error {
+	return currentContext().Start(ctx)
+}
+
+// Stop stops the instances managed by the current scope.  See
+// RegistrationContext.Stop.
+func Stop(ctx context.Context) error {
+	return currentContext().Stop(ctx)
+}
+
+// Verify checks every registration visible from the current scope.  See
+// RegistrationContext.Verify.
+func Verify(construct bool) error {
+	return currentContext().Verify(construct)
+}
+
+// DependencyGraph returns a snapshot of the registrations visible from the
+// current scope, which can be written as DOT or Mermaid.
+func DependencyGraph() *Graph {
+	return currentContext().Graph()
 }
 
 // CreateScope creates a new registration scope.
-// -pushScope if true, this new scope will become global, until Close is called
+// -pushScope if true, this new scope will become the current scope for the package-level
+// functions, on every goroutine, until Close is called.  Pushing is process-wide, so it isn't
+// suitable for goroutines that need different scopes, such as parallel tests or request
+// handlers; give each a scope of its own with PushScope instead.
 func CreateScope(pushScope bool) RegistrationContext {
+	return CreateNamedScope("", pushScope)
+}
+
+// CreateNamedScope creates a new registration scope with the given name, which configuration
+// bindings can register in (see ConfigBinding).  An empty name generates one, like CreateScope.
+// -pushScope if true, this new scope will become the current scope for the package-level
+// functions, see CreateScope.
+func CreateNamedScope(name string, pushScope bool) RegistrationContext {
 
 	var onclose closeHandler
 	var newCtx *registrationContext
 
 	if pushScope {
 		onclose = func() {
-			if currentContext == newCtx {
-				currentContext = newCtx.parent
-			}
+			popCurrent(newCtx)
 		}
 	}
 
-	newCtx = currentContext.createScopeCore(onclose)
+	newCtx = currentContext().createScopeCore(name, onclose)
+	if pushScope {
+		pushCurrent(newCtx)
+	}
 	return newCtx
 }
@@ -63,6 +63,10 @@ func (p *TFail) GodiInit() error {
 	return errors.New("Blargh")
 }
 
+func (p TFail) F1() string {
+	return "fail"
+}
+
 func (p T3) F1() string {
 	return strconv.Itoa(p.n)
 }
@@ -131,24 +135,30 @@ func (s *GoDiTestSuite) TestResolveInstanceFail() {
 
 	t2, err2 := Resolve((*I2)(nil))
 	assert.Nil(s.T(), t2)
-	assert.Equal(s.T(), ErrorRegistrationNotFound, err2.Error())
+	assert.True(s.T(), errors.Is(err2, ErrNotFound))
+
+	var notFound *NotFoundError
+	assert.True(s.T(), errors.As(err2, &notFound))
+	assert.Equal(s.T(), "godi.I2", notFound.TypeName)
+	assert.Equal(s.T(), []string{"root"}, notFound.Scopes)
 
 	res.Close()
 }
 
 func (s *GoDiTestSuite) TestResolveInstanceBad() {
-
-	defer func() {
-		if r := recover(); r != nil {
-			// all good
-		}
-	}()
-
 	i1 := (*I2)(nil)
 	t1 := &T1{}
 
-	RegisterInstanceImplementor(i1, t1)
-	assert.Fail(s.T(), "Should have paniced")
+	res, err := RegisterInstanceImplementor(i1, t1)
+	assert.Nil(s.T(), res)
+
+	var notImpl *NotImplementedError
+	assert.True(s.T(), errors.As(err, &notImpl))
+	assert.Equal(s.T(), "godi.T1", notImpl.Implementor)
+	assert.Equal(s.T(), "godi.I2", notImpl.Target)
+
+	_, err = RegisterTypeImplementor(i1, T1{}, false, nil)
+	assert.True(s.T(), errors.As(err, &notImpl))
 }
 
 func (s *GoDiTestSuite) TestResolveOverride() {
@@ -247,20 +257,17 @@ func (s *GoDiTestSuite) TestInstanceInitializer() {
 }
 
 func (s *GoDiTestSuite) TestResolvePendingFail() {
-	defer func() {
-		if e := recover(); e != nil {
-			assert.False(s.T(), strings.Contains(e.(string), "I1"))
-			assert.True(s.T(), strings.Contains(e.(string), "di.T2"))
-		}
-	}()
-
 	RegisterByName("godi.I1", "godi.T2", false)
 
 	i1 := (*I1)(nil)
 
 	RegisterType(i1)
-	Resolve(i1)
-	s.T().Error("Expected panic.")
+	_, err := Resolve(i1)
+
+	var unknown *UnknownTypeError
+	assert.True(s.T(), errors.As(err, &unknown))
+	assert.Equal(s.T(), "godi.T2", unknown.TypeName)
+	assert.False(s.T(), strings.Contains(err.Error(), "I1"))
 }
 
 func (s *GoDiTestSuite) TestResolvePending() {
@@ -318,18 +325,17 @@ func (s *GoDiTestSuite) TestInitializerInterface() {
 }
 
 func (s *GoDiTestSuite) TestInitializerInterfaceFail() {
-
-	defer func() {
-		if r := recover(); r != nil {
-			// all good
-		}
-	}()
-
 	i1 := (*I1)(nil)
 	RegisterTypeImplementor(i1, TFail{}, true, nil)
 
-	Resolve(i1)
-	assert.Fail(s.T(), "Shouldn't be here")
+	r, err := Resolve(i1)
+	assert.Nil(s.T(), r)
+
+	var initErr *InitError
+	assert.True(s.T(), errors.As(err, &initErr))
+	assert.Equal(s.T(), "godi.TFail", initErr.TypeName)
+	assert.Equal(s.T(), "godi.I1", initErr.Target)
+	assert.Equal(s.T(), "Blargh", initErr.Err.Error())
 }
 
 func (s *GoDiTestSuite) TestInitializeCallback() {
@@ -348,6 +354,36 @@ func (s *GoDiTestSuite) TestInitializeCallback() {
 	assert.Equal(s.T(), "100", r2)
 }
 
+func (s *GoDiTestSuite) TestInitializeCallbackFail() {
+	i1 := (*I1)(nil)
+
+	fail := errors.New("callback")
+	RegisterTypeImplementor(i1, T3{}, false, func(inst interface{}) (bool, error) {
+		return false, fail
+	})
+
+	_, err := Resolve(i1)
+	assert.True(s.T(), errors.Is(err, fail))
+
+	var initErr *InitError
+	assert.True(s.T(), errors.As(err, &initErr))
+}
+
+func (s *GoDiTestSuite) TestResolveNotFoundScopes() {
+	scope := CreateScope(false)
+	defer scope.Close()
+
+	_, err := scope.Resolve((*I1)(nil))
+
+	var notFound *NotFoundError
+	assert.True(s.T(), errors.As(err, &notFound))
+	assert.Equal(s.T(), 2, len(notFound.Scopes))
+	assert.Equal(s.T(), "root", notFound.Scopes[1])
+
+	_, err = ResolveByName("godi.I1")
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+}
+
 func TestGoDiTestSuite(t *testing.T) {
 	suite.Run(t, new(GoDiTestSuite))
 }
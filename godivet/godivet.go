@@ -0,0 +1,508 @@
+// Package godivet statically checks code that uses godi for mistakes that
+// otherwise only show up at runtime:
+//
+//   - a Resolve result asserted to a concrete type that is never registered
+//     for the target and registration name, e.g. r.(*Zebra) when Zebra never
+//     is
+//   - a registration whose implementor doesn't implement its target
+//   - a RegisterByName or ResolveByName name that no RegisterType call
+//     registers
+//
+// Registrations are collected from all of the packages checked together, so
+// check the whole program at once.  Registrations that only exist at
+// runtime, such as those loaded from configuration or overrides, aren't
+// visible to it.
+//
+// It is a standalone go/types driver, so it needs no dependencies beyond the
+// standard library.  See cmd/godivet for the command.
+package godivet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+const godiPath = "github.com/shawnburke/godi"
+
+// The checks, as reported in Diagnostic.Check.
+const (
+	CheckUnregistered = "unregistered"
+	CheckImplements   = "implements"
+	CheckByName       = "byname"
+)
+
+// Diagnostic is a problem found by a check.
+type Diagnostic struct {
+	Pos     token.Position
+	Check   string
+	Message string
+}
+
+func (p Diagnostic) String() string {
+	return fmt.Sprintf("%v: %s", p.Pos, p.Message)
+}
+
+// registration is a registration found in the source.  Types are
+// identified by key, and by-name registrations by godi's type names.
+type registration struct {
+	target string
+	impl   string
+
+	// name is the registration name given with godi.Named, and unknown is
+	// set if it isn't a constant.
+	name    string
+	unknown bool
+
+	byName         bool
+	targetName     string
+	implementsName string
+}
+
+// nameUse is a type name passed to RegisterByName or ResolveByName.
+type nameUse struct {
+	name string
+	pos  token.Pos
+	call string
+}
+
+type checker struct {
+	fset  *token.FileSet
+	diags []Diagnostic
+
+	registrations []registration
+	typeNames     map[string]bool
+	names         []nameUse
+}
+
+// Check runs the checks over pkgs, returning the problems found in order of
+// position.
+func Check(fset *token.FileSet, pkgs []*Package) []Diagnostic {
+	c := &checker{fset: fset, typeNames: map[string]bool{}}
+
+	// registrations are collected from every package before any resolves
+	// are checked against them.
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			c.collect(pkg, f)
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			c.checkResolves(pkg, f)
+		}
+	}
+	for _, use := range c.names {
+		if !c.typeNames[use.name] {
+			c.report(use.pos, CheckByName, "%s: no RegisterType call registers %q", use.call, use.name)
+		}
+	}
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Pos, c.diags[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diags
+}
+
+func (p *checker) report(pos token.Pos, check, format string, args ...interface{}) {
+	p.diags = append(p.diags, Diagnostic{Pos: p.fset.Position(pos), Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+// godiCall returns the name of the godi function or RegistrationContext
+// method call calls, and its identifier.
+func godiCall(info *types.Info, call *ast.CallExpr) (string, *ast.Ident) {
+	fun := ast.Unparen(call.Fun)
+	switch e := fun.(type) {
+	case *ast.IndexExpr:
+		fun = e.X
+	case *ast.IndexListExpr:
+		fun = e.X
+	}
+
+	var id *ast.Ident
+	switch e := fun.(type) {
+	case *ast.SelectorExpr:
+		id = e.Sel
+	case *ast.Ident:
+		id = e
+	default:
+		return "", nil
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != godiPath {
+		return "", nil
+	}
+	return fn.Name(), id
+}
+
+// typeArgs returns the type arguments of a generic godi call.
+func typeArgs(info *types.Info, id *ast.Ident) []types.Type {
+	inst, ok := info.Instances[id]
+	if !ok || inst.TypeArgs == nil {
+		return nil
+	}
+	args := make([]types.Type, inst.TypeArgs.Len())
+	for i := range args {
+		args[i] = inst.TypeArgs.At(i)
+	}
+	return args
+}
+
+// targetType returns the type a godi target argument such as (*Animal)(nil)
+// or Hippo{} refers to, or nil if it can't be known statically.
+func targetType(info *types.Info, expr ast.Expr) types.Type {
+	t := info.TypeOf(expr)
+	if t == nil {
+		return nil
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if _, ok := t.(*types.Named); !ok {
+		return nil
+	}
+	return t
+}
+
+// staticType returns the static type of expr, or nil if it is an interface,
+// since then the dynamic type isn't known.
+func staticType(info *types.Info, expr ast.Expr) types.Type {
+	t := info.TypeOf(expr)
+	if t == nil || types.IsInterface(t) {
+		return nil
+	}
+	return t
+}
+
+func deref(t types.Type) types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
+
+// key identifies a type across packages, since each package is type-checked
+// with its own copies of its imports.
+func key(t types.Type) string {
+	t = deref(t)
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg().Path() + "." + named.Obj().Name()
+	}
+	return types.TypeString(t, nil)
+}
+
+// godiName returns godi's name for t, as used by RegisterByName.
+func godiName(t types.Type) string {
+	t = deref(t)
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		return named.Obj().Pkg().Name() + "." + named.Obj().Name()
+	}
+	return types.TypeString(t, nil)
+}
+
+// registrationName returns the name given by a godi.Named option in opts,
+// and false if it can't be known statically.
+func registrationName(info *types.Info, opts []ast.Expr) (string, bool) {
+	name, known := "", true
+	for _, opt := range opts {
+		call, ok := ast.Unparen(opt).(*ast.CallExpr)
+		if !ok {
+			// a variable may hold any option
+			known = false
+			continue
+		}
+		if fn, _ := godiCall(info, call); fn != "Named" {
+			continue
+		}
+		if s, ok := constString(info, call.Args[0]); ok {
+			name = s
+		} else {
+			known = false
+		}
+	}
+	return name, known
+}
+
+func constString(info *types.Info, expr ast.Expr) (string, bool) {
+	tv := info.Types[expr]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// collect records the registrations in f, and checks their implementors.
+func (p *checker) collect(pkg *Package, f *ast.File) {
+	info := pkg.Info
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		name, id := godiCall(info, call)
+		args := call.Args
+
+		var target, impl types.Type
+		var opts []ast.Expr
+		instance := false
+		switch name {
+		case "RegisterType":
+			if t := targetType(info, args[0]); t != nil {
+				p.typeNames[godiName(t)] = true
+			}
+			return true
+		case "RegisterTypeImplementor":
+			target, impl, opts = targetType(info, args[0]), targetType(info, args[1]), args[4:]
+		case "RegisterDefault":
+			target, impl, opts = targetType(info, args[0]), targetType(info, args[1]), args[3:]
+		case "RegisterInstanceImplementor":
+			target, impl, instance, opts = targetType(info, args[0]), staticType(info, args[1]), true, args[2:]
+		case "RegisterProvider":
+			// provider results are registered as returned, like instances
+			target, instance, opts = targetType(info, args[0]), true, args[3:]
+			if sig, ok := info.TypeOf(args[1]).(*types.Signature); ok && sig.Results().Len() > 0 {
+				impl = sig.Results().At(0).Type()
+			}
+		case "RegisterTypeAs":
+			if targs := typeArgs(info, id); len(targs) == 2 {
+				target, impl, opts = targs[0], targs[1], args[3:]
+			}
+		case "RegisterInstanceAs":
+			if targs := typeArgs(info, id); len(targs) == 1 {
+				target, impl, instance, opts = targs[0], staticType(info, args[1]), true, args[2:]
+			}
+		case "RegisterByName":
+			targetName, ok1 := constString(info, args[0])
+			implName, ok2 := constString(info, args[1])
+			if ok1 {
+				p.names = append(p.names, nameUse{targetName, args[0].Pos(), name})
+			}
+			if ok2 {
+				p.names = append(p.names, nameUse{implName, args[1].Pos(), name})
+			}
+			if ok1 && ok2 {
+				regName, known := registrationName(info, args[3:])
+				p.registrations = append(p.registrations, registration{byName: true, targetName: targetName,
+					implementsName: implName, name: regName, unknown: !known})
+			}
+			return true
+		case "ResolveByName":
+			if targetName, ok := constString(info, args[0]); ok {
+				p.names = append(p.names, nameUse{targetName, args[0].Pos(), name})
+			}
+			return true
+		default:
+			return true
+		}
+
+		if target == nil {
+			return true
+		}
+		regName, known := registrationName(info, opts)
+		reg := registration{target: key(target), name: regName, unknown: !known}
+		if impl != nil && !types.IsInterface(impl) {
+			reg.impl = key(impl)
+			if msg := implementsMessage(impl, target, !instance); msg != "" {
+				p.report(args[0].Pos(), CheckImplements, "%s: %s", name, msg)
+			}
+		}
+		p.registrations = append(p.registrations, reg)
+		return true
+	})
+}
+
+// implementsMessage explains why impl can't be registered for target, or
+// returns "" if it can.  godi creates type registrations as pointers, so
+// the pointer's methods count unless impl is an instance.
+func implementsMessage(impl, target types.Type, pointer bool) string {
+	iface, ok := target.Underlying().(*types.Interface)
+	if !ok {
+		if key(impl) != key(target) {
+			return fmt.Sprintf("%s can't be registered for %s", godiName(impl), godiName(target))
+		}
+		return ""
+	}
+
+	if types.Implements(impl, iface) {
+		return ""
+	}
+	if _, isPtr := impl.(*types.Pointer); pointer && !isPtr && types.Implements(types.NewPointer(impl), iface) {
+		return ""
+	}
+
+	msg := fmt.Sprintf("%s does not implement %s", godiName(impl), godiName(target))
+	if method, wrongType := types.MissingMethod(impl, iface, true); method != nil {
+		if wrongType {
+			msg += fmt.Sprintf(" (wrong type for method %s)", method.Name())
+		} else {
+			msg += fmt.Sprintf(" (missing method %s)", method.Name())
+		}
+	}
+	return msg
+}
+
+// checkResolves checks the type assertions on Resolve results, and the
+// concrete types passed to ResolveAs, in f.
+func (p *checker) checkResolves(pkg *Package, f *ast.File) {
+	info := pkg.Info
+
+	// the variables holding Resolve results, and what was resolved
+	resolved := map[types.Object]lookup{}
+	record := func(lhs ast.Expr, rhs ast.Expr) {
+		call, ok := ast.Unparen(rhs).(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		l := lookup{known: true}
+		switch name, _ := godiCall(info, call); name {
+		case "Resolve", "TryResolve":
+		case "ResolveNamed":
+			l.name, l.known = constString(info, call.Args[1])
+		default:
+			return
+		}
+		id, ok := lhs.(*ast.Ident)
+		if !ok || id.Name == "_" {
+			return
+		}
+		obj := info.Defs[id]
+		if obj == nil {
+			obj = info.Uses[id]
+		}
+		if l.target = targetType(info, call.Args[0]); l.target != nil && obj != nil {
+			resolved[obj] = l
+		}
+	}
+
+	resolvedLookup := func(expr ast.Expr) lookup {
+		if id, ok := ast.Unparen(expr).(*ast.Ident); ok {
+			return resolved[info.Uses[id]]
+		}
+		return lookup{}
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 && len(n.Lhs) > 0 {
+				record(n.Lhs[0], n.Rhs[0])
+			}
+		case *ast.ValueSpec:
+			if len(n.Values) == 1 && len(n.Names) > 0 {
+				record(n.Names[0], n.Values[0])
+			}
+		case *ast.TypeAssertExpr:
+			if l := resolvedLookup(n.X); l.target != nil && n.Type != nil {
+				p.checkResolve(n.Type.Pos(), l, info.TypeOf(n.Type))
+			}
+		case *ast.TypeSwitchStmt:
+			var assert *ast.TypeAssertExpr
+			switch s := n.Assign.(type) {
+			case *ast.ExprStmt:
+				assert, _ = s.X.(*ast.TypeAssertExpr)
+			case *ast.AssignStmt:
+				assert, _ = s.Rhs[0].(*ast.TypeAssertExpr)
+			}
+			if assert == nil {
+				return true
+			}
+			if l := resolvedLookup(assert.X); l.target != nil {
+				for _, stmt := range n.Body.List {
+					for _, expr := range stmt.(*ast.CaseClause).List {
+						if info.Types[expr].IsNil() {
+							continue
+						}
+						p.checkResolve(expr.Pos(), l, info.TypeOf(expr))
+					}
+				}
+			}
+		case *ast.CallExpr:
+			name, id := godiCall(info, n)
+			l := lookup{known: true}
+			switch name {
+			case "ResolveAs", "MustResolveAs", "TryResolveAs":
+			case "ResolveNamedAs":
+				l.name, l.known = constString(info, n.Args[1])
+			default:
+				return true
+			}
+			if targs := typeArgs(info, id); len(targs) == 1 && !types.IsInterface(targs[0]) {
+				l.target = deref(targs[0])
+				p.checkResolve(n.Pos(), l, targs[0])
+			}
+		}
+		return true
+	})
+}
+
+// lookup is what a resolve asked for: its target, and the registration
+// name, which is only known if it is a constant.
+type lookup struct {
+	target types.Type
+	name   string
+	known  bool
+}
+
+// matches returns true if reg can be found by the lookup's name.
+func (p lookup) matches(reg registration) bool {
+	return !p.known || reg.unknown || reg.name == p.name
+}
+
+// describe returns godi's name for t, followed by the lookup's name if any.
+func (p lookup) describe(t types.Type) string {
+	if p.known && p.name != "" {
+		return fmt.Sprintf("%s (named %q)", godiName(t), p.name)
+	}
+	return godiName(t)
+}
+
+// checkResolve reports resolving the lookup as the concrete type asserted,
+// when nothing registers that type for its target and name.
+func (p *checker) checkResolve(pos token.Pos, l lookup, asserted types.Type) {
+	if asserted == nil || types.IsInterface(asserted) {
+		return
+	}
+	target := l.target
+
+	if iface, ok := target.Underlying().(*types.Interface); ok {
+		if !types.Implements(asserted, iface) {
+			p.report(pos, CheckUnregistered, "%s does not implement %s, so this can never succeed", godiName(asserted), godiName(target))
+			return
+		}
+	}
+
+	targetKey, assertedKey := key(target), key(asserted)
+	if targetKey == assertedKey {
+		for _, reg := range p.registrations {
+			if reg.target == targetKey && l.matches(reg) {
+				return
+			}
+		}
+		p.report(pos, CheckUnregistered, "%s is never registered", l.describe(asserted))
+		return
+	}
+	for _, reg := range p.registrations {
+		switch {
+		case !l.matches(reg):
+		case reg.byName:
+			// by-name registrations of types that aren't registered fail,
+			// and are reported separately
+			if reg.targetName == godiName(target) && reg.implementsName == godiName(asserted) && p.typeNames[reg.implementsName] {
+				return
+			}
+		case reg.target == targetKey && (reg.impl == "" || reg.impl == assertedKey):
+			return
+		}
+	}
+	p.report(pos, CheckUnregistered, "%s is never registered for %s", godiName(asserted), l.describe(target))
+}
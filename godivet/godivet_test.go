@@ -0,0 +1,83 @@
+package godivet
+
+import (
+	"fmt"
+	"go/ast"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wants reads the // want `regexp` comments in the files of pkgs, keyed by file:line.
+func wants(t *testing.T, l *Loader, pkgs []*Package) map[string][]*regexp.Regexp {
+	result := map[string][]*regexp.Regexp{}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, group := range f.Comments {
+				for _, c := range group.List {
+					text, ok := strings.CutPrefix(c.Text, "// want ")
+					if !ok {
+						continue
+					}
+					pattern, err := strconv.Unquote(strings.TrimSpace(text))
+					if !assert.NoError(t, err, "Bad want comment") {
+						continue
+					}
+					result[position(l, c)] = append(result[position(l, c)], regexp.MustCompile(pattern))
+				}
+			}
+		}
+	}
+	return result
+}
+
+func position(l *Loader, n ast.Node) string {
+	pos := l.Fset.Position(n.Pos())
+	return fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+}
+
+func TestCheck(t *testing.T) {
+	l := &Loader{Fset: NewLoader().Fset, Root: "testdata/src"}
+	pkgs, err := l.Load("testdata/src/safari", "testdata/src/app")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected := wants(t, l, pkgs)
+	for _, d := range Check(l.Fset, pkgs) {
+		line := fmt.Sprintf("%s:%d", d.Pos.Filename, d.Pos.Line)
+		matched := false
+		for i, pattern := range expected[line] {
+			if pattern.MatchString(d.Message) {
+				expected[line] = append(expected[line][:i], expected[line][i+1:]...)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			t.Errorf("Unexpected diagnostic %v", d)
+		}
+	}
+	for line, patterns := range expected {
+		for _, pattern := range patterns {
+			t.Errorf("%s: no diagnostic matching %q", line, pattern)
+		}
+	}
+}
+
+func TestLoadRecursive(t *testing.T) {
+	l := &Loader{Fset: NewLoader().Fset, Root: "testdata/src"}
+	pkgs, err := l.Load("testdata/src/...")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var paths []string
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.Path)
+	}
+	assert.Equal(t, []string{"app", "safari"}, paths)
+}
@@ -0,0 +1,203 @@
+package godivet
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Package is a parsed and type-checked package to check.
+type Package struct {
+	Path  string
+	Files []*ast.File
+	Types *types.Package
+	Info  *types.Info
+}
+
+// Loader loads packages from source.
+type Loader struct {
+	Fset *token.FileSet
+
+	// Root, if set, is a GOPATH-style src directory: imports found under it
+	// are loaded from there, and the import paths of directories under it
+	// are relative to it.  Tests use it to load packages from testdata.
+	// Other imports, including godi itself, are loaded with the source
+	// importer, and import paths come from the nearest go.mod.
+	Root string
+
+	fallback types.Importer
+	imported map[string]*types.Package
+}
+
+// NewLoader returns a Loader with a new FileSet.
+func NewLoader() *Loader {
+	return &Loader{Fset: token.NewFileSet()}
+}
+
+// Import implements types.Importer.
+func (p *Loader) Import(importPath string) (*types.Package, error) {
+	if pkg := p.imported[importPath]; pkg != nil {
+		return pkg, nil
+	}
+	if p.imported == nil {
+		p.imported = map[string]*types.Package{}
+	}
+
+	if p.Root != "" {
+		if dir := filepath.Join(p.Root, filepath.FromSlash(importPath)); hasGoFiles(dir) {
+			pkg, err := p.load(dir, importPath)
+			if err != nil {
+				return nil, err
+			}
+			p.imported[importPath] = pkg.Types
+			return pkg.Types, nil
+		}
+	}
+
+	if p.fallback == nil {
+		p.fallback = importer.ForCompiler(p.Fset, "source", nil)
+	}
+	pkg, err := p.fallback.Import(importPath)
+	if err != nil {
+		return nil, err
+	}
+	p.imported[importPath] = pkg
+	return pkg, nil
+}
+
+// Load loads the package in each directory.  A directory ending in /...
+// loads every package under it, skipping testdata, vendor and hidden
+// directories.
+func (p *Loader) Load(dirs ...string) ([]*Package, error) {
+	var expanded []string
+	for _, dir := range dirs {
+		root, recursive := strings.CutSuffix(dir, "/...")
+		if !recursive {
+			expanded = append(expanded, dir)
+			continue
+		}
+		err := filepath.WalkDir(root, func(dir string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			name := d.Name()
+			if dir != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if hasGoFiles(dir) {
+				expanded = append(expanded, dir)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pkgs []*Package
+	for _, dir := range expanded {
+		pkg, err := p.load(dir, p.importPath(dir))
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+func hasGoFiles(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	return len(matches) > 0
+}
+
+// importPath works out the import path of dir, from Root or the nearest
+// go.mod.
+func (p *Loader) importPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	if p.Root != "" {
+		if root, err := filepath.Abs(p.Root); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if module := modulePath(filepath.Join(root, "go.mod")); module != "" {
+			rel, _ := filepath.Rel(root, abs)
+			return path.Join(module, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(root) == root {
+			break
+		}
+	}
+	return filepath.Base(abs)
+}
+
+// modulePath returns the module path declared in a go.mod file, if any.
+func modulePath(gomod string) string {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`)
+		}
+	}
+	return ""
+}
+
+// load parses and type-checks the non-test files of the package in dir.
+func (p *Loader) load(dir, importPath string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		f, err := parser.ParseFile(p.Fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No Go files in %s", dir)
+	}
+
+	info := &types.Info{
+		Types:     map[ast.Expr]types.TypeAndValue{},
+		Defs:      map[*ast.Ident]types.Object{},
+		Uses:      map[*ast.Ident]types.Object{},
+		Instances: map[*ast.Ident]types.Instance{},
+	}
+	conf := types.Config{Importer: p}
+	pkg, err := conf.Check(importPath, p.Fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	return &Package{Path: importPath, Files: files, Types: pkg, Info: info}, nil
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"safari"
+
+	"github.com/shawnburke/godi"
+)
+
+func Resolves() {
+	a, _ := godi.Resolve((*safari.Animal)(nil))
+	_ = a.(*safari.Hippo)
+	_ = a.(safari.Lion)
+	_ = a.(*safari.Zebra) // want `Zebra is never registered for safari.Animal`
+	_ = a.(safari.Animal)
+	if _, ok := a.(*safari.Guide); ok { // want `Guide does not implement safari.Animal`
+		return
+	}
+
+	var g interface{}
+	g, _ = godi.TryResolve(safari.Guide{})
+	_ = g.(*safari.Guide)
+
+	var n, _ = godi.ResolveNamed((*safari.Animal)(nil), "lion")
+	switch n.(type) {
+	case safari.Lion, nil:
+	case *safari.Hippo: // want `Hippo is never registered for safari.Animal \(named "lion"\)`
+	case *safari.Zebra: // want `Zebra is never registered for safari.Animal`
+	}
+
+	_ = godi.MustResolveAs[*safari.Guide](nil)
+	_, _ = godi.ResolveAs[*safari.Zebra](nil)              // want `safari.Zebra is never registered$`
+	_, _ = godi.ResolveNamedAs[*safari.Guide](nil, "tour") // want `safari.Guide \(named "tour"\) is never registered`
+}
+
+func ByName() {
+	godi.ResolveByName("safari.Hippo")
+	godi.ResolveByName("safari.Hipo") // want `no RegisterType call registers "safari.Hipo"`
+}
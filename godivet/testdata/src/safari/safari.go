@@ -0,0 +1,50 @@
+package safari
+
+import (
+	"io"
+
+	"github.com/shawnburke/godi"
+)
+
+type Animal interface {
+	Name() string
+}
+
+type Hippo struct{}
+
+func (p *Hippo) Name() string { return "hippo" }
+
+type Zebra struct{}
+
+func (p *Zebra) Name() string { return "zebra" }
+
+type Lion struct{}
+
+func (p Lion) Name() string { return "lion" }
+
+type Rock struct{}
+
+type Guide struct{}
+
+func NewGuide() *Guide { return &Guide{} }
+
+func init() {
+	godi.RegisterType((*Animal)(nil))
+	godi.RegisterType(Hippo{})
+	godi.RegisterType(Lion{})
+	godi.RegisterTypeImplementor((*Animal)(nil), Hippo{}, false, nil)
+	godi.RegisterTypeImplementor((*Animal)(nil), Rock{}, false, nil) // want `Rock does not implement safari.Animal \(missing method Name\)`
+	godi.RegisterInstanceImplementor((*io.Reader)(nil), &Hippo{})    // want `Hippo does not implement io.Reader \(missing method Read\)`
+	godi.RegisterProvider(Guide{}, NewGuide, true)
+	godi.RegisterProvider((*Animal)(nil), func() *Rock { return nil }, false)     // want `Rock does not implement safari.Animal`
+	godi.RegisterProvider((*Animal)(nil), func() Hippo { return Hippo{} }, false) // want `Hippo does not implement safari.Animal`
+	godi.RegisterProvider((*Animal)(nil), func() *Hippo { return nil }, false)
+	godi.RegisterByName("safari.Animal", "safari.Lion", false)
+	godi.RegisterInstanceImplementor((*Animal)(nil), Lion{}, godi.Named("lion"))
+	godi.RegisterByName("safari.Animal", "safari.Zebra", false) // want `"safari.Zebra"`
+
+	scope := godi.CreateScope(false)
+	scope.RegisterTypeImplementor(Guide{}, Hippo{}, false, nil) // want `Hippo can't be registered for safari.Guide`
+	godi.RegisterTypeAs[Animal, Rock](scope, false, nil)        // want `Rock does not implement safari.Animal`
+	godi.RegisterInstanceAs[Animal](scope, Lion{})
+}
@@ -0,0 +1,249 @@
+package godi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// --------
+//
+// Graph export.  A Graph is a snapshot of the registrations visible from a
+// scope: a node for each target, a node for each registration's
+// implementor grouped by the scope it was registered in, "implemented by"
+// edges from targets to implementors and "depends on" edges from
+// implementors to the targets of their provider parameters and injected
+// fields, including those deferred with Lazy or Provider.  It can be
+// written as Graphviz DOT or Mermaid.
+//
+// --------
+
+// Lifetimes reported for implementor nodes.
+const (
+	LifetimeInstance  = "instance"
+	LifetimeCached    = "cached"
+	LifetimeScoped    = "scoped"
+	LifetimeTransient = "transient"
+)
+
+// GraphNode is a target or an implementor.
+type GraphNode struct {
+	ID    string
+	Label string
+
+	// Scope is the scope an implementor was registered in; empty for targets.
+	Scope string
+
+	// Lifetime is one of the Lifetime* constants for implementors; empty for
+	// targets.
+	Lifetime string
+
+	// Missing is set on targets that something requires but that have no
+	// registration.
+	Missing bool
+
+	// Condition describes the profiles or conditions an implementor is
+	// registered with, see InProfile and When, and Inactive is set if they
+	// don't currently hold.
+	Condition string
+	Inactive  bool
+}
+
+// GraphEdge connects two nodes by ID.
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// Graph is a snapshot of the registrations visible from a scope.
+type Graph struct {
+	// Scopes lists scope names, outermost first.
+	Scopes []string
+	Nodes  []*GraphNode
+	Edges  []*GraphEdge
+}
+
+// Graph returns a snapshot of the registrations in this scope, its parents
+// and the override scope.
+func (p *registrationContext) Graph() *Graph {
+	g := &Graph{}
+	targets := map[string]*GraphNode{}
+
+	targetNode := func(label string) *GraphNode {
+		n := targets[label]
+		if n == nil {
+			n = &GraphNode{ID: fmt.Sprintf("n%d", len(g.Nodes)), Label: label}
+			targets[label] = n
+			g.Nodes = append(g.Nodes, n)
+		}
+		return n
+	}
+
+	// outermost first, so the override scope comes last.
+	chain := p.lookupChain()
+	for i := len(chain) - 1; i >= 0; i-- {
+		ctx := chain[i]
+		g.Scopes = append(g.Scopes, ctx.name)
+
+		for _, reg := range ctx.allRegistrations() {
+			target := targetNode(reg.String())
+
+			label := reg.implType.typeName
+			if reg.provider.IsValid() {
+				label += " (provider)"
+			}
+			if reg.isDefault {
+				label += " (default)"
+			}
+			condition, inactive := reg.condition(), !reg.active()
+			if condition != "" {
+				label += " (" + condition + ")"
+			}
+			if inactive {
+				label += " (inactive)"
+			}
+			impl := &GraphNode{
+				ID:        fmt.Sprintf("n%d", len(g.Nodes)),
+				Label:     label,
+				Scope:     ctx.name,
+				Lifetime:  reg.lifetime(),
+				Condition: condition,
+				Inactive:  inactive,
+			}
+			g.Nodes = append(g.Nodes, impl)
+			g.Edges = append(g.Edges, &GraphEdge{From: target.ID, To: impl.ID, Label: "implemented by"})
+
+			deps, _ := reg.dependencies()
+			for _, dep := range deps {
+				depLabel := dep.typeName()
+				if dep.name != "" {
+					depLabel += "[" + dep.name + "]"
+				}
+				depNode := targetNode(depLabel)
+
+				edge := "depends on"
+				if dep.deferred {
+					edge += " (deferred)"
+				}
+				if dep.optional {
+					edge += " (optional)"
+				}
+				g.Edges = append(g.Edges, &GraphEdge{From: impl.ID, To: depNode.ID, Label: edge})
+
+				if !dep.optional {
+					if found, _ := p.lookupRegistration(dep.typeName(), dep.name); found == nil {
+						depNode.Missing = true
+					}
+				}
+			}
+		}
+	}
+	return g
+}
+
+func (p *typeRegistration) lifetime() string {
+	switch {
+	case p.isInstance:
+		return LifetimeInstance
+	case p.scoped:
+		return LifetimeScoped
+	case p.cached:
+		return LifetimeCached
+	}
+	return LifetimeTransient
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with a cluster per scope.
+func (p *Graph) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintln(b, "digraph godi {")
+	fmt.Fprintln(b, "  rankdir=LR;")
+
+	for _, n := range p.Nodes {
+		if n.Scope != "" {
+			continue
+		}
+		style := ""
+		if n.Missing {
+			style = ", style=dashed, color=red"
+		}
+		fmt.Fprintf(b, "  %s [label=%s, shape=ellipse%s];\n", n.ID, dotQuote(n.Label), style)
+	}
+
+	for i, scope := range p.Scopes {
+		fmt.Fprintf(b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(b, "    label=%s;\n", dotQuote(scope))
+		for _, n := range p.Nodes {
+			if n.Scope == scope {
+				style := ""
+				if n.Inactive {
+					style = ", style=dashed"
+				}
+				fmt.Fprintf(b, "    %s [label=%s, shape=box%s];\n", n.ID, dotQuote(n.Label+"\n"+n.Lifetime), style)
+			}
+		}
+		fmt.Fprintln(b, "  }")
+	}
+
+	for _, e := range p.Edges {
+		style := ""
+		if strings.HasPrefix(e.Label, "depends on") {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(b, "  %s -> %s [label=%s%s];\n", e.From, e.To, dotQuote(e.Label), style)
+	}
+
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, with a subgraph per
+// scope.
+func (p *Graph) WriteMermaid(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintln(b, "flowchart LR")
+
+	for _, n := range p.Nodes {
+		if n.Scope == "" {
+			fmt.Fprintf(b, "  %s([%s])\n", n.ID, mermaidQuote(n.Label))
+			if n.Missing {
+				fmt.Fprintf(b, "  style %s stroke:#f00,stroke-dasharray:5\n", n.ID)
+			}
+		}
+	}
+
+	for i, scope := range p.Scopes {
+		fmt.Fprintf(b, "  subgraph s%d [%s]\n", i, mermaidQuote(scope))
+		for _, n := range p.Nodes {
+			if n.Scope == scope {
+				fmt.Fprintf(b, "    %s[%s]\n", n.ID, mermaidQuote(n.Label+"<br/>"+n.Lifetime))
+			}
+		}
+		fmt.Fprintln(b, "  end")
+	}
+
+	for _, e := range p.Edges {
+		arrow := "-->"
+		if strings.HasPrefix(e.Label, "depends on") {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(b, "  %s %s|%s| %s\n", e.From, arrow, mermaidQuote(e.Label), e.To)
+	}
+
+	return b.Flush()
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, "#quot;", -1) + `"`
+}
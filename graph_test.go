@@ -0,0 +1,79 @@
+package godi
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestGraph() {
+	RegisterInstanceImplementor((*I1)(nil), T1{})
+	RegisterTypeImplementor((*I3)(nil), TInjected{}, true, nil)
+
+	g := DependencyGraph()
+	assert.Equal(s.T(), []string{"root"}, g.Scopes)
+
+	var buf bytes.Buffer
+	assert.Nil(s.T(), g.WriteDOT(&buf))
+	assert.Equal(s.T(), `digraph godi {
+  rankdir=LR;
+  n0 [label="godi.I1", shape=ellipse];
+  n2 [label="godi.I3", shape=ellipse];
+  n4 [label="godi.I2", shape=ellipse];
+  n5 [label="godi.T3", shape=ellipse, style=dashed, color=red];
+  subgraph cluster_0 {
+    label="root";
+    n1 [label="godi.T1\ninstance", shape=box];
+    n3 [label="godi.TInjected\ncached", shape=box];
+  }
+  n0 -> n1 [label="implemented by"];
+  n2 -> n3 [label="implemented by"];
+  n3 -> n0 [label="depends on", style=dashed];
+  n3 -> n0 [label="depends on", style=dashed];
+  n3 -> n4 [label="depends on (optional)", style=dashed];
+  n3 -> n5 [label="depends on", style=dashed];
+}
+`, buf.String())
+
+	buf.Reset()
+	assert.Nil(s.T(), g.WriteMermaid(&buf))
+	assert.Equal(s.T(), `flowchart LR
+  n0(["godi.I1"])
+  n2(["godi.I3"])
+  n4(["godi.I2"])
+  n5(["godi.T3"])
+  style n5 stroke:#f00,stroke-dasharray:5
+  subgraph s0 ["root"]
+    n1["godi.T1<br/>instance"]
+    n3["godi.TInjected<br/>cached"]
+  end
+  n0 -->|"implemented by"| n1
+  n2 -->|"implemented by"| n3
+  n3 -.->|"depends on"| n0
+  n3 -.->|"depends on"| n0
+  n3 -.->|"depends on (optional)"| n4
+  n3 -.->|"depends on"| n5
+`, buf.String())
+}
+
+func (s *GoDiTestSuite) TestGraphScopes() {
+	RegisterInstanceImplementor((*I1)(nil), T1{})
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterProvider((*I3)(nil), func(i I1) *TProvided { return nil }, false, Named("p"))
+	scope.RegisterTypeImplementor((*I1)(nil), T2{}, false, nil, Scoped())
+
+	g := scope.Graph()
+	assert.Equal(s.T(), 2, len(g.Scopes))
+	assert.Equal(s.T(), "root", g.Scopes[0])
+
+	var buf bytes.Buffer
+	g.WriteDOT(&buf)
+	dot := buf.String()
+	assert.True(s.T(), strings.Contains(dot, `label="godi.I3[p]"`))
+	assert.True(s.T(), strings.Contains(dot, `label="godi.TProvided (provider)\ntransient"`))
+	assert.True(s.T(), strings.Contains(dot, `label="godi.T2\nscoped"`))
+	assert.True(s.T(), strings.Contains(dot, "subgraph cluster_1"))
+}
@@ -0,0 +1,208 @@
+package godi
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// --------
+//
+// Hooks give cross-cutting visibility into, and control over, the resolve
+// pipeline: counting creations, logging slow initializations, enforcing
+// policies.  A hook is registered on a scope for one phase, and runs for
+// resolves against that scope and its children, outermost scope first and
+// in registration order within a scope.
+//
+// --------
+
+// HookPhase is a point in the resolve pipeline where hooks run.
+type HookPhase int
+
+const (
+	// HookBeforeLookup runs before the scopes are searched for a
+	// registration, once per ResolveAll.  Only Target and Name are set.
+	HookBeforeLookup HookPhase = iota
+
+	// HookRegistrationChosen runs once a registration has been found, before
+	// any cached instance is returned or a new one created.  ResolveAll runs
+	// it for each registration.
+	HookRegistrationChosen
+
+	// HookAfterCreate runs after a new instance has been constructed and its
+	// fields injected, before it is initialized.
+	HookAfterCreate
+
+	// HookAfterInitializer runs after each InitializeCallback, GodiInit or
+	// InstanceInitializer succeeds.  Initializer and Duration are set.
+	HookAfterInitializer
+
+	// HookOnError runs when a resolve fails.  Err is set.
+	HookOnError
+)
+
+var hookPhaseNames = []string{"BeforeLookup", "RegistrationChosen", "AfterCreate", "AfterInitializer", "OnError"}
+
+func (p HookPhase) String() string {
+	if p < 0 || int(p) >= len(hookPhaseNames) {
+		return fmt.Sprintf("HookPhase(%d)", int(p))
+	}
+	return hookPhaseNames[p]
+}
+
+// ResolveEvent describes the resolve a hook is running for.
+type ResolveEvent struct {
+	Phase HookPhase
+
+	// Target and Name are what was asked for.
+	Target string
+	Name   string
+
+	// Scope is the scope being resolved against.
+	Scope string
+
+	// Implementor is the chosen registration's implementor, once known.
+	Implementor string
+
+	// Initializer and Duration describe the step that just ran, for
+	// HookAfterInitializer.
+	Initializer string
+	Duration    time.Duration
+
+	// Instance is the instance so far, if any.  Setting it replaces the
+	// result: before an instance exists, the resolve returns it directly,
+	// and for HookOnError it recovers from the error.
+	Instance interface{}
+
+	// Err is the error the resolve failed with, for HookOnError.
+	Err error
+}
+
+// ResolveHook is called for each event in the phase it was registered for.
+// Returning an error vetoes the resolve, which fails with that error; for
+// HookOnError, it replaces the error.
+type ResolveHook func(event *ResolveEvent) error
+
+type hook struct {
+	fn ResolveHook
+}
+
+// hookCount counts registered hooks, so resolves don't look for them when
+// there are none.
+var hookCount int64
+
+// RegisterHook registers hook to run in phase for resolves against this
+// scope and its children.  Closing the result removes it.
+func (p *registrationContext) RegisterHook(phase HookPhase, fn ResolveHook) Closable {
+	h := &hookToken{context: p, phase: phase, hook: &hook{fn: fn}}
+
+	p.rwlock.Lock()
+	defer p.rwlock.Unlock()
+	p.hooks[phase] = append(p.hooks[phase], h.hook)
+	atomic.AddInt64(&hookCount, 1)
+	return h
+}
+
+type hookToken struct {
+	context *registrationContext
+	phase   HookPhase
+	hook    *hook
+}
+
+func (p *hookToken) Close() {
+	p.context.rwlock.Lock()
+	defer p.context.rwlock.Unlock()
+
+	registered := p.context.hooks[p.phase]
+	for i, h := range registered {
+		if h == p.hook {
+			p.context.hooks[p.phase] = append(registered[:i:i], registered[i+1:]...)
+			atomic.AddInt64(&hookCount, -1)
+			return
+		}
+	}
+}
+
+// getHooks returns a snapshot of this scope's hooks for phase.
+func (p *registrationContext) getHooks(phase HookPhase) []*hook {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+	return append([]*hook(nil), p.hooks[phase]...)
+}
+
+// runHooks runs the hooks for phase visible from this scope, outermost scope
+// first, stopping at the first to return an error.
+func (p *registrationContext) runHooks(phase HookPhase, event *ResolveEvent) error {
+	if atomic.LoadInt64(&hookCount) == 0 {
+		return nil
+	}
+
+	var chain []*registrationContext
+	for ctx := p; ctx != nil; ctx = ctx.getParent() {
+		chain = append(chain, ctx)
+	}
+
+	event.Phase = phase
+	event.Scope = p.name
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, h := range chain[i].getHooks(phase) {
+			if err := h.fn(event); err != nil {
+				if phase == HookOnError {
+					return err
+				}
+				return fmt.Errorf("Resolving '%s' vetoed by %v hook: %w", event.Target, phase, err)
+			}
+		}
+	}
+	return nil
+}
+
+// runInstanceHooks runs the hooks for phase on an instance of reg, returning
+// the instance to use.
+func (p *registrationContext) runInstanceHooks(phase HookPhase, event *ResolveEvent, reg *typeRegistration, instance interface{}) (interface{}, error) {
+	event.Target = reg.targetType.typeName
+	event.Name = reg.name
+	event.Implementor = reg.implType.typeName
+	event.Instance = instance
+	if err := p.runHooks(phase, event); err != nil {
+		return nil, err
+	}
+	if event.Instance == nil {
+		return instance, nil
+	}
+	return event.Instance, nil
+}
+
+// afterCreate runs the HookAfterCreate hooks for a new instance of reg,
+// returning the instance to use.
+func (p *registrationContext) afterCreate(reg *typeRegistration, instance interface{}) (interface{}, error) {
+	if atomic.LoadInt64(&hookCount) == 0 {
+		return instance, nil
+	}
+	return p.runInstanceHooks(HookAfterCreate, &ResolveEvent{}, reg, instance)
+}
+
+// afterInitializer runs the HookAfterInitializer hooks for an
+// initialization step of reg that started at start and succeeded, returning
+// the instance to use.
+func (p *registrationContext) afterInitializer(reg *typeRegistration, step string, start time.Time, instance interface{}) (interface{}, error) {
+	if atomic.LoadInt64(&hookCount) == 0 {
+		return instance, nil
+	}
+	event := &ResolveEvent{Initializer: step, Duration: time.Since(start)}
+	return p.runInstanceHooks(HookAfterInitializer, event, reg, instance)
+}
+
+// onError runs the HookOnError hooks for a failed resolve, which may recover
+// with an instance or replace the error.
+func (p *registrationContext) onError(event *ResolveEvent, err error) (interface{}, error) {
+	event.Instance = nil
+	event.Err = err
+	if hookErr := p.runHooks(HookOnError, event); hookErr != nil {
+		return nil, hookErr
+	}
+	if event.Instance != nil {
+		return event.Instance, nil
+	}
+	return nil, err
+}
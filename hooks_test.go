@@ -0,0 +1,149 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestHookPhases() {
+	RegisterTypeImplementor((*I1)(nil), T3{}, false, func(instance interface{}) (bool, error) {
+		return true, nil
+	})
+
+	var phases []string
+	for _, phase := range []HookPhase{HookBeforeLookup, HookRegistrationChosen, HookAfterCreate, HookAfterInitializer} {
+		RegisterHook(phase, func(e *ResolveEvent) error {
+			assert.Equal(s.T(), "godi.I1", e.Target)
+			assert.Equal(s.T(), "root", e.Scope)
+			label := e.Phase.String()
+			if e.Initializer != "" {
+				label += " " + e.Initializer
+			}
+			phases = append(phases, label)
+			return nil
+		})
+	}
+
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "42", r.(I1).F1())
+	assert.Equal(s.T(), []string{
+		"BeforeLookup",
+		"RegistrationChosen",
+		"AfterCreate",
+		"AfterInitializer InitializeCallback",
+		"AfterInitializer GodiInit",
+	}, phases)
+}
+
+func (s *GoDiTestSuite) TestHookVetoAndReplace() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	policy := errors.New("not allowed")
+	closer := RegisterHook(HookRegistrationChosen, func(e *ResolveEvent) error {
+		if e.Implementor == "godi.T1" {
+			return policy
+		}
+		return nil
+	})
+
+	_, err := Resolve((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, policy))
+	closer.Close()
+
+	RegisterHook(HookAfterCreate, func(e *ResolveEvent) error {
+		e.Instance = &T1{s: "replaced"}
+		return nil
+	})
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "replaced", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestHookVetoResolveAll() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	RegisterTypeImplementor((*I1)(nil), T2{}, false, nil, Named("two"))
+
+	policy := errors.New("not allowed")
+	var chosen []string
+	closer := RegisterHook(HookRegistrationChosen, func(e *ResolveEvent) error {
+		chosen = append(chosen, e.Implementor)
+		if e.Implementor == "godi.T2" {
+			return policy
+		}
+		return nil
+	})
+
+	_, err := ResolveAll((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, policy))
+	assert.Equal(s.T(), []string{"godi.T1", "godi.T2"}, chosen)
+	closer.Close()
+
+	closer = RegisterHook(HookBeforeLookup, func(e *ResolveEvent) error {
+		return policy
+	})
+	_, err = ResolveAll((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, policy))
+	closer.Close()
+
+	// an error hook can recover a vetoed registration
+	RegisterHook(HookRegistrationChosen, func(e *ResolveEvent) error {
+		if e.Name == "two" {
+			return policy
+		}
+		return nil
+	})
+	RegisterHook(HookOnError, func(e *ResolveEvent) error {
+		e.Instance = &T1{s: "recovered"}
+		return nil
+	})
+	all, err := ResolveAll((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, len(all))
+	assert.Equal(s.T(), "recovered", all[1].(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestHookOnError() {
+	var failed []string
+	RegisterHook(HookOnError, func(e *ResolveEvent) error {
+		failed = append(failed, e.Target)
+		if e.Target == "godi.I1" {
+			e.Instance = T1{s: "fallback"}
+		}
+		return nil
+	})
+
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "fallback", r.(I1).F1())
+
+	_, err = Resolve((*I2)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	assert.Equal(s.T(), []string{"godi.I1", "godi.I2"}, failed)
+}
+
+func (s *GoDiTestSuite) TestHooksInherited() {
+	RegisterInstanceImplementor((*I1)(nil), T1{})
+
+	var scopes []string
+	RegisterHook(HookBeforeLookup, func(e *ResolveEvent) error {
+		scopes = append(scopes, "root:"+e.Scope)
+		return nil
+	})
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	closer := scope.RegisterHook(HookBeforeLookup, func(e *ResolveEvent) error {
+		scopes = append(scopes, "child:"+e.Scope)
+		return nil
+	})
+
+	name := scope.(*registrationContext).name
+	scope.Resolve((*I1)(nil))
+	Resolve((*I1)(nil))
+	closer.Close()
+	scope.Resolve((*I1)(nil))
+
+	assert.Equal(s.T(), []string{"root:" + name, "child:" + name, "root:root", "root:" + name}, scopes)
+}
@@ -0,0 +1,153 @@
+package godi
+
+import (
+	"errors"
+	"reflect"
+)
+
+// --------
+//
+// Deferred dependencies.  A Lazy[T] or Provider[T] can be injected wherever
+// a T could be (fields, provider and decorator parameters, or a direct
+// Resolve), but nothing is resolved until Get is called.  They are bound to
+// the scope the consumer was resolved against, and resolve T from it.
+//
+// Since nothing is resolved up front, they also break dependency cycles:
+// if A needs B and B needs A, one side can take a Lazy of the other.  Get
+// called while the consumer is still being resolved, such as from its
+// GodiInit, is part of that resolve, so a real cycle through it is still
+// reported as a *CycleError, including one that calls Get on the same Lazy
+// again.
+//
+// --------
+
+// deferred is implemented by *Lazy[T] and *Provider[T].
+type deferred interface {
+	bind(state *resolveState, scope *registrationContext, name string)
+	deferredType() reflect.Type
+}
+
+var deferredInterface = reflect.TypeOf((*deferred)(nil)).Elem()
+
+var errUnbound = errors.New("Not bound to a scope: Lazy and Provider values must be injected or resolved by godi")
+
+// Lazy resolves T the first time Get is called, and returns the same
+// instance after that.  Copies of a Lazy share its instance.
+type Lazy[T any] struct {
+	state *lazyState
+}
+
+type lazyState struct {
+	state *resolveState
+	scope *registrationContext
+	name  string
+
+	// slot holds the instance once resolved.  Like a cached registration,
+	// only one Get resolves it at a time, and a Get that re-enters that
+	// resolve fails with a *CycleError rather than waiting for itself.
+	slot instanceSlot
+}
+
+func (p *Lazy[T]) bind(state *resolveState, scope *registrationContext, name string) {
+	p.state = &lazyState{state: state, scope: scope, name: name}
+}
+
+func (p *Lazy[T]) deferredType() reflect.Type {
+	return typeOf[T]()
+}
+
+// Get resolves T on the first successful call, and returns that instance
+// from then on.  If resolving fails, the next call tries again.  Calls made
+// while another is resolving T wait for it.
+func (p Lazy[T]) Get() (T, error) {
+	var zero T
+	if p.state == nil {
+		return zero, errUnbound
+	}
+
+	s := p.state
+	state := s.state.active()
+	if state == nil {
+		state = newResolveState()
+		defer state.leave()
+	}
+
+	raw, err := s.slot.realize(state, func() (interface{}, error) {
+		return resolveDeferred[T](state, s.scope, s.name)
+	})
+	if err != nil {
+		return zero, err
+	}
+	value, _ := raw.(T)
+	return value, nil
+}
+
+// Provider resolves T each time Get is called, so a transient registration
+// returns a new instance every time.
+type Provider[T any] struct {
+	state *resolveState
+	scope *registrationContext
+	name  string
+}
+
+func (p *Provider[T]) bind(state *resolveState, scope *registrationContext, name string) {
+	p.state, p.scope, p.name = state, scope, name
+}
+
+func (p *Provider[T]) deferredType() reflect.Type {
+	return typeOf[T]()
+}
+
+// Get resolves T from the scope the Provider was bound to.
+func (p Provider[T]) Get() (T, error) {
+	if p.scope == nil {
+		var zero T
+		return zero, errUnbound
+	}
+	return resolveDeferred[T](p.state, p.scope, p.name)
+}
+
+// resolveDeferred resolves T from scope, as part of the resolve state was
+// bound in if that is still running.
+func resolveDeferred[T any](state *resolveState, scope *registrationContext, name string) (T, error) {
+	raw, err := scope.resolveCore(state.active(), typeOf[T](), name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convertTo[T](raw)
+}
+
+// deferredElem returns the Lazy or Provider type t refers to, either
+// directly or through a pointer, and whether it is one.
+func deferredElem(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(deferredInterface)
+}
+
+// deferredTarget returns the T of a Lazy[T] or Provider[T] type.
+func deferredTarget(t reflect.Type) (reflect.Type, bool) {
+	elem, ok := deferredElem(t)
+	if !ok {
+		return nil, false
+	}
+	return reflect.New(elem).Interface().(deferred).deferredType(), true
+}
+
+// bindDeferred returns a new Lazy or Provider of type t, which may be a
+// pointer, bound to this scope and state.  Returns false if t isn't one.
+func (p *registrationContext) bindDeferred(state *resolveState, t reflect.Type, name string) (reflect.Value, bool) {
+	elem, ok := deferredElem(t)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.New(elem)
+	v.Interface().(deferred).bind(state, p, name)
+	if t.Kind() == reflect.Ptr {
+		return v, true
+	}
+	return v.Elem(), true
+}
@@ -0,0 +1,202 @@
+package godi
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	TLazy struct {
+		Lazy     Lazy[I1]      `godi:""`
+		Provider *Provider[I1] `godi:""`
+	}
+
+	// TLazyA and TLazyB depend on each other, with A's side deferred.
+	TLazyA struct {
+		B Lazy[ICycleB] `godi:""`
+	}
+	TLazyB struct {
+		A ICycleA `godi:""`
+	}
+)
+
+func (p *TLazyA) A() {}
+func (p *TLazyB) B() {}
+
+func (s *GoDiTestSuite) TestLazyAndProviderFields() {
+	calls := 0
+	RegisterProvider((*I1)(nil), func() *T1 {
+		calls++
+		return &T1{s: "t1"}
+	}, false)
+	RegisterTypeImplementor(TLazy{}, TLazy{}, false, nil)
+
+	r, err := Resolve(TLazy{})
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 0, calls)
+
+	t := r.(*TLazy)
+	l1, err := t.Lazy.Get()
+	assert.Nil(s.T(), err)
+	l2, _ := t.Lazy.Get()
+	assert.True(s.T(), l1 == l2)
+	assert.Equal(s.T(), 1, calls)
+
+	p1, _ := t.Provider.Get()
+	p2, _ := t.Provider.Get()
+	assert.False(s.T(), p1 == p2)
+	assert.Equal(s.T(), 3, calls)
+}
+
+func (s *GoDiTestSuite) TestLazyBoundToScope() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+	RegisterProvider((*I3)(nil), func(l Lazy[I1]) *TProvided {
+		dep, _ := l.Get()
+		return &TProvided{dep: dep}
+	}, false)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "scope"})
+
+	r, err := scope.Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "scope", r.(I3).F3())
+
+	// direct resolution
+	p, err := ResolveAs[Provider[I1]](scope)
+	assert.Nil(s.T(), err)
+	i1, _ := p.Get()
+	assert.Equal(s.T(), "scope", i1.F1())
+
+	l, err := ResolveAs[*Lazy[I1]](nil)
+	assert.Nil(s.T(), err)
+	i1, _ = l.Get()
+	assert.Equal(s.T(), "root", i1.F1())
+}
+
+func (s *GoDiTestSuite) TestLazyBreaksCycle() {
+	RegisterTypeImplementor((*ICycleA)(nil), TLazyA{}, true, nil)
+	RegisterTypeImplementor((*ICycleB)(nil), TLazyB{}, true, nil)
+
+	r, err := Resolve((*ICycleA)(nil))
+	assert.Nil(s.T(), err)
+
+	a := r.(*TLazyA)
+	b, err := a.B.Get()
+	assert.Nil(s.T(), err)
+	assert.True(s.T(), b.(*TLazyB).A == a)
+
+	assert.Nil(s.T(), Verify(false))
+
+	var labels []string
+	for _, e := range DependencyGraph().Edges {
+		labels = append(labels, e.Label)
+	}
+	assert.Contains(s.T(), labels, "depends on (deferred)")
+}
+
+// TLazyReentrant's GodiInit gets its Lazy, whose provider calls Get on the
+// same Lazy again.
+type TLazyReentrant struct {
+	Dep Lazy[I1] `godi:""`
+}
+
+var lazyReentrant *TLazyReentrant
+
+func (p *TLazyReentrant) GodiInit() error {
+	lazyReentrant = p
+	_, err := p.Dep.Get()
+	return err
+}
+
+func (s *GoDiTestSuite) TestLazyReentrant() {
+	RegisterProvider((*I1)(nil), func() (I1, error) {
+		return lazyReentrant.Dep.Get()
+	}, false)
+	RegisterTypeImplementor(TLazyReentrant{}, TLazyReentrant{}, false, nil)
+
+	done := make(chan error)
+	go func() {
+		_, err := Resolve(TLazyReentrant{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		var cycle *CycleError
+		assert.True(s.T(), errors.As(err, &cycle))
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("Lazy.Get deadlocked on re-entry")
+	}
+}
+
+func (s *GoDiTestSuite) TestLazyConcurrentGet() {
+	calls := int32(0)
+	RegisterProvider((*I1)(nil), func() *T1 {
+		atomic.AddInt32(&calls, 1)
+		return &T1{s: "t1"}
+	}, false)
+	RegisterTypeImplementor(TLazy{}, TLazy{}, false, nil)
+
+	r, _ := Resolve(TLazy{})
+	t := r.(*TLazy)
+
+	wait := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, err := t.Lazy.Get()
+			assert.Nil(s.T(), err)
+		}()
+	}
+	wait.Wait()
+	assert.Equal(s.T(), int32(1), atomic.LoadInt32(&calls))
+}
+
+func (s *GoDiTestSuite) TestLazyInCachedBoundToRegistrationScope() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+	RegisterTypeImplementor(TLazy{}, TLazy{}, true, nil)
+
+	// the cached consumer belongs to the root scope, so its Lazy resolves
+	// from root even though a child resolved it first and has since closed
+	scope := CreateScope(false)
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "scope"})
+	r, err := scope.Resolve(TLazy{})
+	assert.Nil(s.T(), err)
+	scope.Close()
+
+	i1, err := r.(*TLazy).Lazy.Get()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "root", i1.F1())
+}
+
+func (s *GoDiTestSuite) TestLazyErrors() {
+	var unbound Lazy[I1]
+	_, err := unbound.Get()
+	assert.NotNil(s.T(), err)
+
+	var provider Provider[I1]
+	_, err = provider.Get()
+	assert.NotNil(s.T(), err)
+
+	l, _ := ResolveAs[Lazy[I1]](nil)
+	_, err = l.Get()
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "later"})
+	i1, err := l.Get()
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "later", i1.F1())
+
+	// deferred dependencies are still checked by Verify
+	Reset()
+	RegisterTypeImplementor(TLazy{}, TLazy{}, false, nil)
+	err = Verify(false)
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,207 @@
+package godi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// --------
+//
+// Lifecycle management for cached instances godi creates.
+//
+// When a cached registration creates an instance that is Startable,
+// Stoppable or an io.Closer, the scope that owns the registration keeps
+// track of it.  Starting the scope starts those instances in the order they
+// finished being created, which is dependency order since an instance's
+// dependencies are created first.  Stopping the scope, or closing it, stops
+// them in reverse order.  Stopped instances stay cached, and are started
+// again if the scope is.
+//
+// --------
+
+// Startable is implemented by instances that need to be started when their
+// scope is started.
+type Startable interface {
+	Start(ctx context.Context) error
+}
+
+// Stoppable is implemented by instances that need to be stopped when their
+// scope is stopped or closed.  Instances that are also an io.Closer are
+// closed once they have stopped.
+type Stoppable interface {
+	Stop(ctx context.Context) error
+}
+
+// LifecycleTimeout bounds each individual Start, Stop or Close call.
+var LifecycleTimeout = 30 * time.Second
+
+type managedInstance struct {
+	reg      *typeRegistration
+	instance interface{}
+	started  bool
+	closed   bool
+	lock     sync.Mutex
+}
+
+type lifecycle struct {
+	sync.Mutex
+	started bool
+	managed []*managedInstance
+}
+
+func isManaged(instance interface{}) bool {
+	switch instance.(type) {
+	case Startable, Stoppable, io.Closer:
+		return true
+	}
+	return false
+}
+
+// manage tracks an instance created for one of this scope's cached
+// registrations.  If the scope has already been started, the instance is
+// started immediately.
+func (p *registrationContext) manage(reg *typeRegistration, instance interface{}) error {
+	if !isManaged(instance) {
+		return nil
+	}
+
+	mi := &managedInstance{reg: reg, instance: instance}
+
+	p.lifecycle.Lock()
+	started := p.lifecycle.started
+	p.lifecycle.Unlock()
+
+	if started {
+		if err := mi.start(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	p.lifecycle.Lock()
+	defer p.lifecycle.Unlock()
+	p.lifecycle.managed = append(p.lifecycle.managed, mi)
+	return nil
+}
+
+// Start starts the instances created so far in dependency order, and marks
+// the scope as started so instances created later are started as they are
+// created.  Errors from each instance are joined together.
+func (p *registrationContext) Start(ctx context.Context) error {
+	p.lifecycle.Lock()
+	p.lifecycle.started = true
+	managed := append([]*managedInstance(nil), p.lifecycle.managed...)
+	p.lifecycle.Unlock()
+
+	var errs []error
+	for _, mi := range managed {
+		if err := mi.start(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop stops the instances started by this scope in the reverse of the
+// order they were created, and closes its io.Closer instances.  The
+// instances are still tracked, so starting the scope again starts them
+// again.  Errors from each instance are joined together.
+func (p *registrationContext) Stop(ctx context.Context) error {
+	p.lifecycle.Lock()
+	managed := append([]*managedInstance(nil), p.lifecycle.managed...)
+	p.lifecycle.started = false
+	p.lifecycle.Unlock()
+
+	return stopAll(ctx, managed)
+}
+
+// stopRegistration stops any instances created for reg.
+func (p *registrationContext) stopRegistration(ctx context.Context, reg *typeRegistration) error {
+	p.lifecycle.Lock()
+	var stopping, remaining []*managedInstance
+	for _, mi := range p.lifecycle.managed {
+		if mi.reg == reg {
+			stopping = append(stopping, mi)
+		} else {
+			remaining = append(remaining, mi)
+		}
+	}
+	p.lifecycle.managed = remaining
+	p.lifecycle.Unlock()
+
+	return stopAll(ctx, stopping)
+}
+
+func stopAll(ctx context.Context, managed []*managedInstance) error {
+	var errs []error
+	for i := len(managed) - 1; i >= 0; i-- {
+		if err := managed[i].stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *managedInstance) start(ctx context.Context) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.started {
+		return nil
+	}
+	if s, ok := p.instance.(Startable); ok {
+		if err := callHook(ctx, s.Start); err != nil {
+			return fmt.Errorf("Error starting '%s': %w", p.reg.implType.typeName, err)
+		}
+	}
+	p.started = true
+	return nil
+}
+
+// stop stops the instance if it was started, then closes it if it's an
+// io.Closer.  A closer is closed whether or not it was started, since it may
+// hold resources from when it was created, but only once, and not if
+// stopping it failed.
+func (p *managedInstance) stop(ctx context.Context) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	started := p.started
+	p.started = false
+
+	if s, ok := p.instance.(Stoppable); ok && started {
+		if err := callHook(ctx, s.Stop); err != nil {
+			return fmt.Errorf("Error stopping '%s': %w", p.reg.implType.typeName, err)
+		}
+	}
+
+	if c, ok := p.instance.(io.Closer); ok && !p.closed {
+		p.closed = true
+		if err := callHook(ctx, func(context.Context) error { return c.Close() }); err != nil {
+			return fmt.Errorf("Error closing '%s': %w", p.reg.implType.typeName, err)
+		}
+	}
+	return nil
+}
+
+// callHook runs hook, giving up after LifecycleTimeout even if the hook
+// ignores its context.
+func callHook(ctx context.Context, hook func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, LifecycleTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
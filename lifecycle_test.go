@@ -0,0 +1,232 @@
+package godi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	lifecycleEvents struct {
+		sync.Mutex
+		events []string
+	}
+	TLifeA struct {
+		B ICycleB `godi:""`
+	}
+	TLifeB      struct{}
+	TLifeCloser struct{}
+	TLifeSlow   struct{}
+	TLifeFail   struct{}
+	TLifeBoth   struct{ fail bool }
+)
+
+var events lifecycleEvents
+
+func (p *lifecycleEvents) add(e string) {
+	p.Lock()
+	defer p.Unlock()
+	p.events = append(p.events, e)
+}
+
+func (p *lifecycleEvents) reset() []string {
+	p.Lock()
+	defer p.Unlock()
+	e := p.events
+	p.events = nil
+	return e
+}
+
+func (p *TLifeA) A() {}
+func (p *TLifeA) Start(ctx context.Context) error {
+	events.add("start a")
+	return nil
+}
+func (p *TLifeA) Stop(ctx context.Context) error {
+	events.add("stop a")
+	return nil
+}
+
+func (p *TLifeB) B() {}
+func (p *TLifeB) Start(ctx context.Context) error {
+	events.add("start b")
+	return nil
+}
+func (p *TLifeB) Stop(ctx context.Context) error {
+	events.add("stop b")
+	return nil
+}
+
+func (p *TLifeCloser) F1() string { return "closer" }
+func (p *TLifeCloser) Close() error {
+	events.add("close")
+	return nil
+}
+
+func (p *TLifeSlow) F1() string { return "slow" }
+func (p *TLifeSlow) Stop(ctx context.Context) error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+func (p *TLifeFail) F3() string { return "fail" }
+func (p *TLifeFail) Stop(ctx context.Context) error {
+	return errors.New("stop failed")
+}
+
+func (p *TLifeBoth) F1() string { return "both" }
+func (p *TLifeBoth) Start(ctx context.Context) error {
+	events.add("start")
+	return nil
+}
+func (p *TLifeBoth) Stop(ctx context.Context) error {
+	events.add("stop")
+	if p.fail {
+		return errors.New("stop failed")
+	}
+	return nil
+}
+func (p *TLifeBoth) Close() error {
+	events.add("close")
+	return nil
+}
+
+func (s *GoDiTestSuite) TestLifecycleOrder() {
+	events.reset()
+	scope := CreateScope(false)
+	scope.RegisterTypeImplementor((*ICycleA)(nil), TLifeA{}, true, nil)
+	scope.RegisterTypeImplementor((*ICycleB)(nil), TLifeB{}, true, nil)
+
+	_, err := scope.Resolve((*ICycleA)(nil))
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), events.reset())
+
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.Equal(s.T(), []string{"start b", "start a"}, events.reset())
+
+	// starting again doesn't restart anything
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.Nil(s.T(), events.reset())
+
+	scope.Close()
+	assert.Equal(s.T(), []string{"stop a", "stop b"}, events.reset())
+}
+
+func (s *GoDiTestSuite) TestLifecycleStartOnCreate() {
+	events.reset()
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterTypeImplementor((*ICycleB)(nil), TLifeB{}, true, nil)
+
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.Nil(s.T(), events.reset())
+
+	scope.Resolve((*ICycleB)(nil))
+	assert.Equal(s.T(), []string{"start b"}, events.reset())
+
+	assert.Nil(s.T(), scope.Stop(context.Background()))
+	assert.Equal(s.T(), []string{"stop b"}, events.reset())
+}
+
+func (s *GoDiTestSuite) TestLifecycleTransientAndToken() {
+	events.reset()
+	// transient instances aren't managed
+	RegisterTypeImplementor((*ICycleB)(nil), TLifeB{}, false, nil)
+	Resolve((*ICycleB)(nil))
+
+	token, _ := RegisterTypeImplementor((*I1)(nil), TLifeCloser{}, true, nil)
+	Resolve((*I1)(nil))
+	Resolve((*I1)(nil))
+
+	token.Close()
+	assert.Equal(s.T(), []string{"close"}, events.reset())
+
+	assert.Nil(s.T(), Stop(context.Background()))
+	assert.Nil(s.T(), events.reset())
+}
+
+func (s *GoDiTestSuite) TestLifecycleErrors() {
+	timeout := LifecycleTimeout
+	LifecycleTimeout = 10 * time.Millisecond
+	defer func() { LifecycleTimeout = timeout }()
+
+	RegisterTypeImplementor((*I1)(nil), TLifeSlow{}, true, nil)
+	RegisterTypeImplementor((*I3)(nil), TLifeFail{}, true, nil)
+	Resolve((*I1)(nil))
+	Resolve((*I3)(nil))
+	assert.Nil(s.T(), Start(context.Background()))
+
+	err := Stop(context.Background())
+	assert.True(s.T(), errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(s.T(), err.Error(), "stop failed")
+	assert.Contains(s.T(), err.Error(), "godi.TLifeSlow")
+}
+
+func (s *GoDiTestSuite) TestLifecycleRestart() {
+	events.reset()
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterTypeImplementor((*ICycleB)(nil), TLifeB{}, true, nil)
+
+	first, _ := scope.Resolve((*ICycleB)(nil))
+
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.Nil(s.T(), scope.Stop(context.Background()))
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.Equal(s.T(), []string{"start b", "stop b", "start b"}, events.reset())
+
+	// the instance is still cached, and started
+	second, _ := scope.Resolve((*ICycleB)(nil))
+	assert.True(s.T(), first == second)
+
+	scope.Close()
+	assert.Equal(s.T(), []string{"stop b"}, events.reset())
+}
+
+func (s *GoDiTestSuite) TestLifecycleStopNotStarted() {
+	events.reset()
+	scope := CreateScope(false)
+	scope.RegisterTypeImplementor((*ICycleB)(nil), TLifeB{}, true, nil)
+	scope.RegisterTypeImplementor((*I1)(nil), TLifeCloser{}, true, nil)
+	scope.Resolve((*ICycleB)(nil))
+	scope.Resolve((*I1)(nil))
+
+	// instances that were never started aren't stopped, but closers are
+	// closed, once.
+	assert.Nil(s.T(), scope.Stop(context.Background()))
+	assert.Equal(s.T(), []string{"close"}, events.reset())
+
+	scope.Close()
+	assert.Nil(s.T(), events.reset())
+}
+
+func (s *GoDiTestSuite) TestLifecycleStopAndClose() {
+	events.reset()
+	scope := CreateScope(false)
+	scope.RegisterTypeImplementor((*I1)(nil), TLifeBoth{}, true, nil)
+	scope.Resolve((*I1)(nil))
+
+	// an instance that is both is stopped, then closed once.
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.Nil(s.T(), scope.Stop(context.Background()))
+	assert.Equal(s.T(), []string{"start", "stop", "close"}, events.reset())
+
+	scope.Close()
+	assert.Nil(s.T(), events.reset())
+
+	// it isn't closed if stopping it fails.
+	scope = CreateScope(false)
+	defer scope.Close()
+	scope.RegisterTypeImplementor((*I1)(nil), TLifeBoth{}, true, func(instance interface{}) (bool, error) {
+		instance.(*TLifeBoth).fail = true
+		return true, nil
+	})
+	scope.Resolve((*I1)(nil))
+
+	assert.Nil(s.T(), scope.Start(context.Background()))
+	assert.NotNil(s.T(), scope.Stop(context.Background()))
+	assert.Equal(s.T(), []string{"start", "stop"}, events.reset())
+}
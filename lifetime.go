@@ -0,0 +1,58 @@
+package godi
+
+import (
+	"context"
+)
+
+// --------
+//
+// Scoped lifetimes create one instance per scope resolved against, rather
+// than one per registration (cached) or one per resolve (transient).  The
+// instance belongs to that scope and is stopped when the scope is closed.
+//
+// Scopes can be carried in a context.Context, so that request handlers
+// resolve against their own scope rather than the global one.
+//
+// --------
+
+// Scoped makes a registration create one instance for each scope it is
+// resolved from.  The instance is managed by that scope (see Startable and
+// Stoppable) and stopped when the scope is closed.  This overrides the
+// registration's cached setting.
+func Scoped() RegistrationOption {
+	return func(p *typeRegistration) {
+		p.scoped = true
+	}
+}
+
+// scopedInstance returns this scope's slot for reg's instance.
+func (p *registrationContext) scopedInstance(reg *typeRegistration) *instanceSlot {
+	p.rwlock.Lock()
+	defer p.rwlock.Unlock()
+
+	si := p.scoped[reg.id]
+	if si == nil {
+		si = &instanceSlot{}
+		p.scoped[reg.id] = si
+	}
+	return si
+}
+
+type scopeKey struct{}
+
+// WithScope returns a copy of ctx that carries scope.
+func WithScope(ctx context.Context, scope RegistrationContext) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope carried by ctx, or nil if there isn't one.
+func ScopeFrom(ctx context.Context) RegistrationContext {
+	scope, _ := ctx.Value(scopeKey{}).(RegistrationContext)
+	return scope
+}
+
+// ResolveContext resolves target from the scope carried by ctx, falling back
+// to the current global scope if ctx doesn't carry one.
+func ResolveContext(ctx context.Context, target interface{}) (interface{}, error) {
+	return contextOrCurrent(ScopeFrom(ctx)).Resolve(target)
+}
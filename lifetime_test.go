@@ -0,0 +1,92 @@
+package godi
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestScopedLifetime() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil, Scoped())
+
+	s1 := CreateScope(false)
+	defer s1.Close()
+	s2 := CreateScope(false)
+	defer s2.Close()
+
+	a1, _ := s1.Resolve((*I1)(nil))
+	a2, _ := s1.Resolve((*I1)(nil))
+	b1, _ := s2.Resolve((*I1)(nil))
+
+	assert.True(s.T(), a1 == a2)
+	assert.False(s.T(), a1 == b1)
+}
+
+func (s *GoDiTestSuite) TestScopedLifetimeClose() {
+	events.reset()
+	RegisterTypeImplementor((*I1)(nil), TLifeCloser{}, false, nil, Scoped())
+
+	scope := CreateScope(false)
+	scope.Resolve((*I1)(nil))
+	scope.Resolve((*I1)(nil))
+	assert.Nil(s.T(), events.reset())
+
+	scope.Close()
+	assert.Equal(s.T(), []string{"close"}, events.reset())
+
+	// the registration's scope doesn't own the scoped instances
+	assert.Nil(s.T(), Stop(context.Background()))
+	assert.Nil(s.T(), events.reset())
+}
+
+func (s *GoDiTestSuite) TestResolveContext() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "request"})
+
+	ctx := context.Background()
+	assert.Nil(s.T(), ScopeFrom(ctx))
+
+	r, err := ResolveContext(ctx, (*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "root", r.(I1).F1())
+
+	ctx = WithScope(ctx, scope)
+	assert.Equal(s.T(), scope, ScopeFrom(ctx))
+
+	r, err = ResolveContext(ctx, (*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "request", r.(I1).F1())
+
+	typed, err := ResolveAs[I1](ScopeFrom(ctx))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "request", typed.F1())
+}
+
+type TSingleton struct {
+	Req I1 `godi:""`
+}
+
+func (p *TSingleton) F3() string { return p.Req.F1() }
+
+func (s *GoDiTestSuite) TestScopedInCachedResolvedFromChild() {
+	events.reset()
+	RegisterTypeImplementor((*I1)(nil), TLifeCloser{}, false, nil, Scoped())
+	RegisterTypeImplementor((*I3)(nil), TSingleton{}, true, nil)
+
+	// the singleton belongs to the root scope, so it gets the root scope's
+	// instance even when first resolved from a child.
+	child := CreateScope(false)
+	r, err := child.Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+	child.Close()
+	assert.Nil(s.T(), events.reset())
+
+	req, _ := Resolve((*I1)(nil))
+	assert.True(s.T(), r.(*TSingleton).Req == req)
+
+	again, _ := Resolve((*I3)(nil))
+	assert.True(s.T(), r == again)
+}
@@ -0,0 +1,360 @@
+package godi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// --------
+//
+// Modules bundle related registrations, such as those a shared library
+// needs, so that they can be installed into a scope with one call and
+// removed again by closing the result.
+//
+//	var Storage = godi.NewModule("storage").
+//		Requires((*Config)(nil)).
+//		Provides((*Store)(nil)).
+//		RegisterTypeImplementor((*Store)(nil), SQLStore{}, true, nil).
+//		RegisterDecorator((*Store)(nil), withMetrics).
+//		Include(Logging)
+//
+//	closer, err := godi.Install(Storage)
+//
+// --------
+
+// Module is a named set of registrations, decorators, instance initializers
+// and sub-modules, installed together with Install.  The Register* methods
+// record the registrations to make, which are checked and made when the
+// module is installed, so an error, such as an implementor that doesn't
+// implement its target, is returned by Install.  They return the module, so
+// that calls can be chained.
+//
+// A module should be built once, typically in a package variable, and not
+// changed after it has been installed.
+type Module struct {
+	name     string
+	provides []interface{}
+	requires []interface{}
+	includes []*Module
+	steps    []moduleStep
+}
+
+// moduleStep makes one of a module's registrations in a scope.
+type moduleStep func(ctx *registrationContext) (Closable, error)
+
+// NewModule returns an empty module.  The name identifies it in errors.
+func NewModule(name string) *Module {
+	return &Module{name: name}
+}
+
+// Name returns the module's name.
+func (p *Module) Name() string {
+	return p.name
+}
+
+// Provides declares targets the module registers, which Install checks
+// are registered once the module is installed.  Targets are passed as to
+// Resolve, or by name as for RegisterByName.
+func (p *Module) Provides(targets ...interface{}) *Module {
+	p.provides = append(p.provides, targets...)
+	return p
+}
+
+// Requires declares targets the module depends on but doesn't register,
+// such as configuration the application provides.  Install checks them
+// before making any registrations, and fails if one isn't registered in the
+// scope or its parents, unless the module or one of its sub-modules
+// declares it with Provides.
+func (p *Module) Requires(targets ...interface{}) *Module {
+	p.requires = append(p.requires, targets...)
+	return p
+}
+
+// Include makes modules sub-modules of this one, installed before the rest
+// of its registrations, in order.  A module included by several others is
+// only installed once in a scope.
+func (p *Module) Include(modules ...*Module) *Module {
+	for _, module := range modules {
+		module := module
+		p.includes = append(p.includes, module)
+		p.steps = append(p.steps, func(ctx *registrationContext) (Closable, error) {
+			if err := ctx.installModule(module); err != nil {
+				return nil, err
+			}
+			return &moduleRelease{context: ctx, module: module}, nil
+		})
+	}
+	return p
+}
+
+// Register adds a registration made by fn, for anything the other methods
+// don't cover, such as hooks or value sources.  The Closable fn returns, if
+// any, is closed when the module is removed.  fn should register through
+// the ctx it is given, whose Install returns an error: use Include for
+// sub-modules instead.
+func (p *Module) Register(fn func(ctx RegistrationContext) (Closable, error)) *Module {
+	p.steps = append(p.steps, func(ctx *registrationContext) (Closable, error) {
+		return fn(&installingScope{registrationContext: ctx, module: p})
+	})
+	return p
+}
+
+// installingScope is the scope passed to a Register function.  The scope's
+// moduleLock is held while the module is installed, so it rejects Install
+// rather than deadlocking.
+type installingScope struct {
+	*registrationContext
+	module *Module
+}
+
+func (p *installingScope) Install(module *Module) (Closable, error) {
+	return nil, fmt.Errorf("Module '%s' can't install module '%s' while being installed, use Include instead", p.module.name, module.name)
+}
+
+// RegisterTypeImplementor adds a registration, see
+// RegistrationContext.RegisterTypeImplementor.
+func (p *Module) RegisterTypeImplementor(target interface{}, implementorType interface{}, cached bool, init InitializeCallback, opts ...RegistrationOption) *Module {
+	return p.Register(func(ctx RegistrationContext) (Closable, error) {
+		return ctx.RegisterTypeImplementor(target, implementorType, cached, init, opts...)
+	})
+}
+
+// RegisterInstanceImplementor adds a registration, see
+// RegistrationContext.RegisterInstanceImplementor.
+func (p *Module) RegisterInstanceImplementor(target interface{}, instance interface{}, opts ...RegistrationOption) *Module {
+	return p.Register(func(ctx RegistrationContext) (Closable, error) {
+		return ctx.RegisterInstanceImplementor(target, instance, opts...)
+	})
+}
+
+// RegisterProvider adds a registration, see RegistrationContext.RegisterProvider.
+func (p *Module) RegisterProvider(target interface{}, provider interface{}, cached bool, opts ...RegistrationOption) *Module {
+	return p.Register(func(ctx RegistrationContext) (Closable, error) {
+		return ctx.RegisterProvider(target, provider, cached, opts...)
+	})
+}
+
+// RegisterByName adds a registration, see RegistrationContext.RegisterByName.
+// Unlike RegisterByName, the types are checked when the module is
+// installed, as they are for LoadConfig.
+func (p *Module) RegisterByName(target string, implementor string, cached bool, opts ...RegistrationOption) *Module {
+	return p.Register(func(ctx RegistrationContext) (Closable, error) {
+		if err := checkNamedTypes(target, implementor); err != nil {
+			return nil, err
+		}
+		return ctx.RegisterByName(target, implementor, cached, opts...), nil
+	})
+}
+
+// RegisterDefault adds a registration, see RegistrationContext.RegisterDefault.
+func (p *Module) RegisterDefault(target interface{}, implementorType interface{}, cached bool, opts ...RegistrationOption) *Module {
+	return p.Register(func(ctx RegistrationContext) (Closable, error) {
+		return ctx.RegisterDefault(target, implementorType, cached, opts...)
+	})
+}
+
+// RegisterDecorator adds a decorator, see RegistrationContext.RegisterDecorator.
+func (p *Module) RegisterDecorator(target interface{}, decorator interface{}) *Module {
+	return p.Register(func(ctx RegistrationContext) (Closable, error) {
+		return ctx.RegisterDecorator(target, decorator)
+	})
+}
+
+// RegisterInstanceInitializer adds an instance initializer, see
+// RegisterInstanceInitializer.  Unlike those registered directly, it is
+// removed when the module is.
+func (p *Module) RegisterInstanceInitializer(initializer InstanceInitializer) *Module {
+	p.steps = append(p.steps, func(ctx *registrationContext) (Closable, error) {
+		return ctx.addInitializer(initializer), nil
+	})
+	return p
+}
+
+// --------
+//
+// Installing modules
+//
+// --------
+
+// installedModule is a module installed in a scope.
+type installedModule struct {
+	refs       int
+	installing bool
+	closers    configRegistrations
+}
+
+// Install makes the module's registrations, and those of its sub-modules,
+// in this scope.  Installing a module that is already installed in the
+// scope does nothing, and closing the result only removes the module's
+// registrations once every Install of it has been closed.  Modules are
+// installed separately in each scope, so installing one in a child scope
+// installs it again there.
+//
+// The module's required targets, and those of its sub-modules, are checked
+// before anything is registered, and its provided targets once it is
+// installed.  If a registration fails or a target is missing, everything
+// installed is removed again and the errors are returned; missing targets
+// are *MissingTargetError.
+func (p *registrationContext) Install(module *Module) (Closable, error) {
+	p.moduleLock.Lock()
+	defer p.moduleLock.Unlock()
+
+	if err := p.checkRequires(module); err != nil {
+		return nil, err
+	}
+	if err := p.installModule(module); err != nil {
+		return nil, err
+	}
+	if err := p.checkModule(module); err != nil {
+		p.releaseModule(module)
+		return nil, err
+	}
+	return &moduleToken{context: p, module: module}, nil
+}
+
+// installModule installs module, or adds a reference to it if it is
+// already installed.  It must be called with moduleLock held.
+func (p *registrationContext) installModule(module *Module) error {
+	if p.modules == nil {
+		p.modules = map[*Module]*installedModule{}
+	}
+	if installed := p.modules[module]; installed != nil {
+		if installed.installing {
+			return fmt.Errorf("Module '%s' includes itself", module.name)
+		}
+		installed.refs++
+		return nil
+	}
+
+	installed := &installedModule{refs: 1, installing: true}
+	p.modules[module] = installed
+	for _, step := range module.steps {
+		closer, err := step(p)
+		if err != nil {
+			delete(p.modules, module)
+			installed.closers.Close()
+			return fmt.Errorf("Installing module '%s': %w", module.name, err)
+		}
+		if closer != nil {
+			installed.closers = append(installed.closers, closer)
+		}
+	}
+	installed.installing = false
+	return nil
+}
+
+// releaseModule drops a reference to an installed module, removing its
+// registrations when there are none left.  It must be called with
+// moduleLock held.
+func (p *registrationContext) releaseModule(module *Module) {
+	installed := p.modules[module]
+	if installed == nil {
+		return
+	}
+	if installed.refs--; installed.refs > 0 {
+		return
+	}
+	delete(p.modules, module)
+	installed.closers.Close()
+}
+
+// walkModules calls fn for module and each of its sub-modules once,
+// sub-modules first.
+func walkModules(module *Module, fn func(m *Module)) {
+	seen := map[*Module]bool{}
+
+	var walk func(m *Module)
+	walk = func(m *Module) {
+		if seen[m] {
+			return
+		}
+		seen[m] = true
+		for _, sub := range m.includes {
+			walk(sub)
+		}
+		fn(m)
+	}
+	walk(module)
+}
+
+// checkRequires checks that the required targets of module and its
+// sub-modules are registered already, or provided by one of them.
+func (p *registrationContext) checkRequires(module *Module) error {
+	provided := map[string]bool{}
+	walkModules(module, func(m *Module) {
+		for _, target := range m.provides {
+			provided[moduleTargetName(target)] = true
+		}
+	})
+
+	var errs []error
+	walkModules(module, func(m *Module) {
+		for _, target := range m.requires {
+			if name := moduleTargetName(target); !provided[name] && !p.hasRegistration(name) {
+				errs = append(errs, &MissingTargetError{Module: m.name, TypeName: name})
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// checkModule checks that the provided targets of module and its
+// sub-modules are registered.
+func (p *registrationContext) checkModule(module *Module) error {
+	var errs []error
+	walkModules(module, func(m *Module) {
+		for _, target := range m.provides {
+			if name := moduleTargetName(target); !p.hasRegistration(name) {
+				errs = append(errs, &MissingTargetError{Module: m.name, TypeName: name, Provided: true})
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// moduleTargetName returns the type name of a target passed to Provides or
+// Requires.
+func moduleTargetName(target interface{}) string {
+	if name, ok := target.(string); ok {
+		return formatType(name)
+	}
+	return typeToString(instanceToType(target))
+}
+
+// hasRegistration returns true if any scope in the lookup chain has a
+// registration for typeName, named or not.
+func (p *registrationContext) hasRegistration(typeName string) bool {
+	for _, ctx := range p.lookupChain() {
+		if regs, _ := ctx.findAllRegistrations(typeName); len(regs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleToken is returned by Install.
+type moduleToken struct {
+	context *registrationContext
+	module  *Module
+	once    sync.Once
+}
+
+func (p *moduleToken) Close() {
+	p.once.Do(func() {
+		p.context.moduleLock.Lock()
+		defer p.context.moduleLock.Unlock()
+		p.context.releaseModule(p.module)
+	})
+}
+
+// moduleRelease releases a sub-module when its including module is
+// removed, with moduleLock already held.
+type moduleRelease struct {
+	context *registrationContext
+	module  *Module
+}
+
+func (p *moduleRelease) Close() {
+	p.context.releaseModule(p.module)
+}
@@ -0,0 +1,162 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestModuleInstall() {
+	base := NewModule("base").
+		Provides(T2{}, (*I2)(nil)).
+		RegisterTypeImplementor(T2{}, T2{}, true, nil)
+	app := NewModule("app").
+		Include(base).
+		Provides((*I1)(nil)).
+		Requires(T2{}).
+		RegisterTypeImplementor((*I1)(nil), T1{}, false, nil).
+		RegisterDecorator((*I1)(nil), decorateWith("app")).
+		RegisterInstanceInitializer(TestInitializer{})
+
+	// base doesn't register the I2 it claims to provide
+	_, err := Install(app)
+	var missing *MissingTargetError
+	assert.True(s.T(), errors.As(err, &missing))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	assert.Equal(s.T(), "Module 'base' provides 'godi.I2', but doesn't register it", err.Error())
+
+	// nothing was left registered
+	_, err = Resolve((*I1)(nil))
+	assert.NotNil(s.T(), err)
+
+	base.provides = base.provides[:1]
+	closer, err := Install(app)
+	assert.Nil(s.T(), err)
+
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), initS+"+app", r.(I1).F1())
+	_, err = Resolve(T2{})
+	assert.Nil(s.T(), err)
+
+	closer.Close()
+	closer.Close()
+	_, err = Resolve((*I1)(nil))
+	assert.NotNil(s.T(), err)
+	_, err = Resolve(T2{})
+	assert.NotNil(s.T(), err)
+
+	// the initializer was removed with the module
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	r, _ = Resolve((*I1)(nil))
+	assert.Equal(s.T(), "", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestModuleIdempotent() {
+	base := NewModule("base").RegisterTypeImplementor(T2{}, T2{}, false, nil)
+	a := NewModule("a").Include(base)
+	b := NewModule("b").Include(base).RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	closeA, err := Install(a)
+	assert.Nil(s.T(), err)
+	closeB, err := Install(b)
+	assert.Nil(s.T(), err)
+	closeB2, err := Install(b)
+	assert.Nil(s.T(), err)
+
+	all, _ := ResolveAll(T2{})
+	assert.Len(s.T(), all, 1)
+	all, _ = ResolveAll((*I1)(nil))
+	assert.Len(s.T(), all, 1)
+
+	// b is still installed until both of its installs are closed, and base
+	// until a is closed too
+	closeB.Close()
+	all, _ = ResolveAll((*I1)(nil))
+	assert.Len(s.T(), all, 1)
+	closeB2.Close()
+	all, _ = ResolveAll((*I1)(nil))
+	assert.Len(s.T(), all, 0)
+	all, _ = ResolveAll(T2{})
+	assert.Len(s.T(), all, 1)
+	closeA.Close()
+	all, _ = ResolveAll(T2{})
+	assert.Len(s.T(), all, 0)
+
+	// installing in a child scope installs it again there
+	Install(a)
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.Install(a)
+	all, _ = scope.ResolveAll(T2{})
+	assert.Len(s.T(), all, 2)
+}
+
+func (s *GoDiTestSuite) TestModuleRequires() {
+	m := NewModule("needy").
+		Requires((*I1)(nil), "godi.T2").
+		RegisterTypeImplementor(T3{}, T3{}, false, nil)
+
+	_, err := Install(m)
+	assert.Equal(s.T(), "Module 'needy' requires 'godi.I1', which is not registered\n"+
+		"Module 'needy' requires 'godi.T2', which is not registered", err.Error())
+	_, err = Resolve(T3{})
+	assert.NotNil(s.T(), err)
+
+	// requirements are checked before any step runs
+	ran := false
+	_, err = Install(NewModule("checked").
+		Requires((*I1)(nil)).
+		Register(func(ctx RegistrationContext) (Closable, error) {
+			ran = true
+			return nil, nil
+		}))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	assert.False(s.T(), ran)
+
+	// or by a module installed with it that provides them
+	provider := NewModule("provider").
+		Provides((*I1)(nil)).
+		RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	closer, err := Install(NewModule("consumer").Requires((*I1)(nil)).Include(provider))
+	assert.Nil(s.T(), err)
+	closer.Close()
+
+	// requirements can be met by a parent scope
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	RegisterTypeImplementor(T2{}, T2{}, false, nil)
+	scope := CreateScope(false)
+	defer scope.Close()
+	_, err = scope.Install(m)
+	assert.Nil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestModuleErrors() {
+	bad := NewModule("bad").
+		RegisterTypeImplementor(T2{}, T2{}, false, nil).
+		RegisterTypeImplementor((*I2)(nil), T1{}, false, nil)
+	m := NewModule("outer").Include(bad)
+
+	_, err := Install(m)
+	var notImpl *NotImplementedError
+	assert.True(s.T(), errors.As(err, &notImpl))
+	assert.Contains(s.T(), err.Error(), "Installing module 'outer': Installing module 'bad': ")
+	_, err = Resolve(T2{})
+	assert.NotNil(s.T(), err)
+
+	// name-based registrations are checked when installed
+	_, err = Install(NewModule("named").RegisterByName("godi.I1", "godi.Nope", false))
+	var unknown *UnknownTypeError
+	assert.True(s.T(), errors.As(err, &unknown))
+
+	nested := NewModule("nested").Register(func(ctx RegistrationContext) (Closable, error) {
+		return ctx.Install(NewModule("inner"))
+	})
+	_, err = Install(nested)
+	assert.Equal(s.T(), "Installing module 'nested': Module 'nested' can't install module 'inner' while being installed, use Include instead", err.Error())
+
+	loop := NewModule("loop")
+	loop.Include(loop)
+	_, err = Install(loop)
+	assert.Equal(s.T(), "Installing module 'loop': Module 'loop' includes itself", err.Error())
+}
@@ -0,0 +1,65 @@
+package godi
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestResolveAll() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "a"})
+	RegisterTypeImplementor((*I1)(nil), T2{}, false, nil)
+	RegisterTypeImplementor((*I1)(nil), T3{}, true, nil)
+
+	all, err := ResolveAll((*I1)(nil))
+	assert.Nil(s.T(), err)
+
+	var names []string
+	for _, a := range all {
+		names = append(names, a.(I1).F1())
+	}
+	assert.Equal(s.T(), []string{"a", "t2", "42"}, names)
+
+	// Resolve still returns the latest registration
+	r, _ := Resolve((*I1)(nil))
+	assert.Equal(s.T(), "42", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestResolveAllScopes() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "child"})
+
+	all, err := ResolveAllAs[I1](scope)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, len(all))
+	assert.Equal(s.T(), "root", all[0].F1())
+	assert.Equal(s.T(), "child", all[1].F1())
+
+	shadow := scope.CreateScope()
+	defer shadow.Close()
+	shadow.RegisterInstanceImplementor((*I1)(nil), T1{s: "shadow"}, ShadowParents())
+
+	all, err = ResolveAllAs[I1](shadow)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 1, len(all))
+	assert.Equal(s.T(), "shadow", all[0].F1())
+}
+
+func (s *GoDiTestSuite) TestResolveAllEmpty() {
+	all, err := ResolveAll((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 0, len(all))
+
+	typed, err := ResolveAllAs[I1](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 0, len(typed))
+}
+
+func (s *GoDiTestSuite) TestResolveAllError() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "a"})
+	RegisterTypeImplementor((*I1)(nil), TFail{}, false, nil)
+
+	_, err := ResolveAll((*I1)(nil))
+	assert.NotNil(s.T(), err)
+}
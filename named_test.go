@@ -0,0 +1,78 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TNamedInjected struct {
+	Primary I1 `godi:"name=primary"`
+	Replica I1 `godi:"name=replica"`
+	Other   I1 `godi:"name=other,optional"`
+}
+
+func (s *GoDiTestSuite) TestResolveNamed() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "primary"}, Named("primary"))
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "replica"}, Named("replica"))
+
+	r, err := ResolveNamed((*I1)(nil), "primary")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "primary", r.(I1).F1())
+
+	replica, err := ResolveNamedAs[I1](nil, "replica")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "replica", replica.F1())
+
+	// unnamed resolves don't see named registrations
+	_, err = Resolve((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+
+	_, err = ResolveNamed((*I1)(nil), "other")
+	var notFound *NotFoundError
+	assert.True(s.T(), errors.As(err, &notFound))
+	assert.Equal(s.T(), "other", notFound.Name)
+
+	all, _ := ResolveAll((*I1)(nil))
+	assert.Equal(s.T(), 2, len(all))
+}
+
+func (s *GoDiTestSuite) TestResolveNamedScopes() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"}, Named("primary"))
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "child"}, Named("replica"))
+
+	r, err := scope.ResolveNamed((*I1)(nil), "primary")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "root", r.(I1).F1())
+
+	r, err = scope.ResolveNamed((*I1)(nil), "replica")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "child", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestResolveNamedByName() {
+	RegisterType((*I1)(nil))
+	RegisterType(T2{})
+	RegisterByName("godi.I1", "godi.T2", false, Named("two"))
+
+	r, err := ResolveNamed((*I1)(nil), "two")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "t2", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestNamedFieldInjection() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "primary"}, Named("primary"))
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "replica"}, Named("replica"))
+	RegisterTypeImplementor(TNamedInjected{}, TNamedInjected{}, false, nil)
+
+	r, err := Resolve(TNamedInjected{})
+	assert.Nil(s.T(), err)
+
+	inst := r.(*TNamedInjected)
+	assert.Equal(s.T(), "primary", inst.Primary.F1())
+	assert.Equal(s.T(), "replica", inst.Replica.F1())
+	assert.Nil(s.T(), inst.Other)
+}
@@ -0,0 +1,151 @@
+package godi
+
+import (
+	"errors"
+	"reflect"
+)
+
+// --------
+//
+// Optional dependencies and defaults.
+//
+// A dependency can be made optional with the "optional" field tag option,
+// by wrapping it in Optional[T], or by resolving it with TryResolve.  Any of
+// these resolve to the zero value when nothing is registered for the
+// target, rather than failing.
+//
+// Default registrations let libraries ship sane implementations that
+// applications can override: they are only used when no scope in the chain
+// has a regular registration for the target.
+//
+// --------
+
+// Optional holds a dependency that may not be registered.  It can be used as
+// a provider or decorator parameter, an injected field, or resolved
+// directly.
+type Optional[T any] struct {
+	Value   T
+	Present bool
+}
+
+// Get returns the value and whether it was registered.
+func (p Optional[T]) Get() (T, bool) {
+	return p.Value, p.Present
+}
+
+// optional is implemented by *Optional[T].
+type optional interface {
+	optionalType() reflect.Type
+	set(value reflect.Value)
+}
+
+var optionalInterface = reflect.TypeOf((*optional)(nil)).Elem()
+
+func (p *Optional[T]) optionalType() reflect.Type {
+	return typeOf[T]()
+}
+
+func (p *Optional[T]) set(value reflect.Value) {
+	p.Value = value.Interface().(T)
+	p.Present = true
+}
+
+// optionalElem returns the Optional type t refers to, either directly or
+// through a pointer, and whether it is one.
+func optionalElem(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(optionalInterface)
+}
+
+// optionalTarget returns the T of an Optional[T] type.
+func optionalTarget(t reflect.Type) (reflect.Type, bool) {
+	elem, ok := optionalElem(t)
+	if !ok {
+		return nil, false
+	}
+	return reflect.New(elem).Interface().(optional).optionalType(), true
+}
+
+// resolveOptional resolves an Optional of type t, which may be a pointer,
+// from this scope as part of state.  Returns false if t isn't one.
+func (p *registrationContext) resolveOptional(state *resolveState, t reflect.Type, name string) (reflect.Value, bool, error) {
+	elem, ok := optionalElem(t)
+	if !ok {
+		return reflect.Value{}, false, nil
+	}
+
+	v := reflect.New(elem)
+	o := v.Interface().(optional)
+
+	target := o.optionalType()
+	raw, err := p.resolveCore(state, target, name)
+	switch {
+	case isMissing(err, target, name):
+	case err != nil:
+		return reflect.Value{}, true, err
+	default:
+		value, err := assignableValue(raw, target)
+		if err != nil {
+			return reflect.Value{}, true, err
+		}
+		o.set(value)
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return v, true, nil
+	}
+	return v.Elem(), true, nil
+}
+
+// isMissing returns true if err says nothing is registered for t itself,
+// rather than for one of its dependencies.
+func isMissing(err error, t reflect.Type, name string) bool {
+	var notFound *NotFoundError
+	return errors.As(err, &notFound) && notFound.TypeName == typeToString(t) && notFound.Name == name
+}
+
+// TryResolve is like Resolve, but returns nil rather than an error if
+// nothing is registered for target.  Other errors, including missing
+// dependencies of the registered implementor, are still returned.
+func (p *registrationContext) TryResolve(target interface{}) (interface{}, error) {
+	return p.tryResolve(nil, target)
+}
+
+func (p *registrationContext) tryResolve(state *resolveState, target interface{}) (interface{}, error) {
+	t := instanceToType(target)
+	instance, err := p.resolveCore(state, t, "")
+	if isMissing(err, t, "") {
+		return nil, nil
+	}
+	return instance, err
+}
+
+// TryResolveAs is like ResolveAs, but returns the zero value and false
+// rather than an error if nothing is registered for T.  If ctx is nil, the
+// current global scope is used.
+func TryResolveAs[T any](ctx RegistrationContext) (T, bool, error) {
+	var zero T
+	raw, err := contextOrCurrent(ctx).TryResolve((*T)(nil))
+	if err != nil || raw == nil {
+		return zero, false, err
+	}
+	v, err := convertTo[T](raw)
+	return v, err == nil, err
+}
+
+// AsDefault makes a registration a default, which is only used when no
+// scope in the chain has a regular registration for the same target and
+// name.  Between defaults, the innermost scope wins as usual.
+func AsDefault() RegistrationOption {
+	return func(p *typeRegistration) {
+		p.isDefault = true
+	}
+}
+
+// RegisterDefault registers implementorType as the default implementor of
+// target, see AsDefault.
+func (p *registrationContext) RegisterDefault(target interface{}, implementorType interface{}, cached bool, opts ...RegistrationOption) (Closable, error) {
+	return p.RegisterTypeImplementor(target, implementorType, cached, nil, append(opts, AsDefault())...)
+}
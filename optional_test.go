@@ -0,0 +1,101 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	TOptional struct {
+		Maybe Optional[I1] `godi:""`
+	}
+
+	// TNeedsI2 has a required dependency that is never registered.
+	TNeedsI2 struct {
+		Dep I2 `godi:""`
+	}
+)
+
+func (p *TNeedsI2) F1() string { return "needs" }
+
+func (s *GoDiTestSuite) TestOptionalParameter() {
+	RegisterProvider((*I3)(nil), func(dep Optional[I1]) *TProvided {
+		if i1, ok := dep.Get(); ok {
+			return &TProvided{dep: i1}
+		}
+		return &TProvided{dep: T1{s: "none"}}
+	}, false)
+
+	r, err := Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "none", r.(I3).F3())
+
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "some"})
+	r, _ = Resolve((*I3)(nil))
+	assert.Equal(s.T(), "some", r.(I3).F3())
+}
+
+func (s *GoDiTestSuite) TestOptionalField() {
+	RegisterTypeImplementor(TOptional{}, TOptional{}, false, nil)
+
+	r, err := Resolve(TOptional{})
+	assert.Nil(s.T(), err)
+	assert.False(s.T(), r.(*TOptional).Maybe.Present)
+
+	assert.Nil(s.T(), Verify(false))
+}
+
+func (s *GoDiTestSuite) TestOptionalMissingDependency() {
+	// an implementor that is registered but can't be built is still an error
+	RegisterTypeImplementor((*I1)(nil), TNeedsI2{}, false, nil)
+
+	_, err := ResolveAs[Optional[I1]](nil)
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+
+	_, err = TryResolve((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+}
+
+func (s *GoDiTestSuite) TestTryResolve() {
+	r, err := TryResolve((*I1)(nil))
+	assert.Nil(s.T(), r)
+	assert.Nil(s.T(), err)
+
+	i1, ok, err := TryResolveAs[I1](nil)
+	assert.Nil(s.T(), i1)
+	assert.False(s.T(), ok)
+	assert.Nil(s.T(), err)
+
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "found"})
+	i1, ok, _ = TryResolveAs[I1](nil)
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "found", i1.F1())
+}
+
+func (s *GoDiTestSuite) TestRegisterDefault() {
+	RegisterDefault((*I1)(nil), T2{}, false)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+
+	r, err := scope.Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "t2", r.(I1).F1())
+
+	// a regular registration in any scope wins over a default, even a
+	// default in an inner scope.
+	scope.RegisterDefault((*I1)(nil), T3{}, false)
+	closer, _ := RegisterInstanceImplementor((*I1)(nil), T1{s: "app"})
+
+	r, _ = scope.Resolve((*I1)(nil))
+	assert.Equal(s.T(), "app", r.(I1).F1())
+	all, _ := scope.ResolveAll((*I1)(nil))
+	assert.Equal(s.T(), 1, len(all))
+
+	closer.Close()
+	r, _ = scope.Resolve((*I1)(nil))
+	assert.Equal(s.T(), "42", r.(I1).F1())
+	all, _ = scope.ResolveAll((*I1)(nil))
+	assert.Equal(s.T(), 2, len(all))
+}
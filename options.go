@@ -0,0 +1,29 @@
+package godi
+
+// --------
+//
+// Registration options adjust how a registration behaves.  They are passed
+// as the trailing arguments to the Register* functions.
+//
+// --------
+
+// RegistrationOption configures a registration.
+type RegistrationOption func(*typeRegistration)
+
+// ShadowParents makes a registration hide the registrations for the same
+// target in parent scopes from ResolveAll.  By default, ResolveAll appends
+// a scope's registrations to those of its parents.
+func ShadowParents() RegistrationOption {
+	return func(p *typeRegistration) {
+		p.shadowParents = true
+	}
+}
+
+// Named gives a registration a name, so that several implementors of the
+// same target can be registered and looked up individually with
+// ResolveNamed.  Resolve only returns unnamed registrations.
+func Named(name string) RegistrationOption {
+	return func(p *typeRegistration) {
+		p.name = name
+	}
+}
@@ -0,0 +1,299 @@
+package godi
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// --------
+//
+// Overrides swap implementations without a rebuild, from environment
+// variables or a command-line flag.  They are name-based registrations, as
+// made by RegisterByName, kept in a dedicated override scope that every
+// scope consults before itself and its parents, so an override wins over
+// any registration for the same target and name.
+//
+// An override is written as
+//
+//	target=implementor[,cached][,name=registration-name]
+//
+// e.g. "safari.Animal=safari.Zebra,cached".
+//
+// --------
+
+// OverrideEnvPrefix is the prefix of environment variables read by
+// LoadEnvOverrides.  The rest of the variable name is the target, with the
+// first "_" standing for the "." after the package and "__" for an
+// underscore in either name, and the value is the rest of the override:
+// GODI_BIND_safari_Animal=safari.Zebra,cached, or
+// GODI_BIND_big__cats_Lion=big_cats.Tiger for big_cats.Lion.
+const OverrideEnvPrefix = "GODI_BIND_"
+
+// OverrideFlagName is the flag registered by RegisterOverrideFlag.
+const OverrideFlagName = "godi.bind"
+
+// Override is an active override.
+type Override struct {
+	Target      string
+	Implementor string
+	Name        string
+	Cached      bool
+
+	// Source describes where the override came from, e.g. the environment
+	// variable or flag.
+	Source string
+}
+
+func (p *Override) String() string {
+	s := p.Target + "=" + p.Implementor
+	if p.Cached {
+		s += ",cached"
+	}
+	if p.Name != "" {
+		s += ",name=" + p.Name
+	}
+	return s
+}
+
+// overrides holds the override scope, which never changes, and the active
+// overrides.  count mirrors len(active) so that resolves can check for
+// overrides without taking the lock.
+var overrides = struct {
+	sync.Mutex
+	context *registrationContext
+	active  []*Override
+	count   int64
+}{context: newOverrideContext()}
+
+func newOverrideContext() *registrationContext {
+	p := newregistrationContext(nil)
+	p.name = "overrides"
+	return p
+}
+
+// overrideContext returns the override scope, or nil if no overrides are
+// active.
+func overrideContext() *registrationContext {
+	if atomic.LoadInt64(&overrides.count) == 0 {
+		return nil
+	}
+	return overrides.context
+}
+
+// ParseOverride parses an override written as
+// "target=implementor[,cached][,name=registration-name]".
+func ParseOverride(spec string) (*Override, error) {
+	target, rest, ok := strings.Cut(spec, "=")
+	parts := strings.Split(rest, ",")
+
+	o := &Override{Target: strings.TrimSpace(target), Implementor: strings.TrimSpace(parts[0])}
+	if !ok || o.Target == "" || o.Implementor == "" {
+		return nil, fmt.Errorf("Invalid override '%s', expected target=implementor[,cached][,name=registration-name]", spec)
+	}
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "cached":
+			o.Cached = true
+		case strings.HasPrefix(part, "name="):
+			o.Name = strings.TrimPrefix(part, "name=")
+		default:
+			return nil, fmt.Errorf("Invalid override '%s', unknown option '%s'", spec, part)
+		}
+	}
+	return o, nil
+}
+
+// ApplyOverride registers o in the override scope.  Both of its types must
+// have been registered with RegisterType, and the implementor must
+// implement the target.  Closing the result removes the override.
+func ApplyOverride(o *Override) (Closable, error) {
+	if err := checkOverride(o); err != nil {
+		return nil, err
+	}
+
+	var opts []RegistrationOption
+	if o.Name != "" {
+		opts = append(opts, Named(o.Name))
+	}
+
+	overrides.Lock()
+	defer overrides.Unlock()
+
+	token := overrides.context.RegisterByName(o.Target, o.Implementor, o.Cached, opts...)
+	overrides.active = append(overrides.active, o)
+	atomic.StoreInt64(&overrides.count, int64(len(overrides.active)))
+	return &overrideToken{token: token, override: o}, nil
+}
+
+// checkOverride checks that o's types have been registered, and that the
+// implementor implements the target.
+func checkOverride(o *Override) error {
+	if err := checkNamedTypes(o.Target, o.Implementor); err != nil {
+		return fmt.Errorf("Invalid override '%s' from %s: %w", o, o.Source, err)
+	}
+	return nil
+}
+
+type overrideToken struct {
+	token    Closable
+	override *Override
+	once     sync.Once
+}
+
+func (p *overrideToken) Close() {
+	p.once.Do(func() {
+		p.token.Close()
+
+		overrides.Lock()
+		defer overrides.Unlock()
+		for i, a := range overrides.active {
+			if a == p.override {
+				overrides.active = append(overrides.active[:i], overrides.active[i+1:]...)
+				atomic.StoreInt64(&overrides.count, int64(len(overrides.active)))
+				break
+			}
+		}
+	})
+}
+
+// LoadEnvOverrides applies an override for each variable in environ, as
+// returned by os.Environ, that starts with OverrideEnvPrefix.  Every
+// variable is checked first, and if any are invalid, none are applied and
+// the errors are joined together.  Closing the result removes the
+// overrides.
+func LoadEnvOverrides(environ []string) (Closable, error) {
+	var loaded []*Override
+	var errs []error
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, OverrideEnvPrefix) {
+			continue
+		}
+
+		target := envOverrideTarget(strings.TrimPrefix(key, OverrideEnvPrefix))
+		o, err := ParseOverride(target + "=" + value)
+		if err == nil {
+			o.Source = "environment variable " + key
+			err = checkOverride(o)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		loaded = append(loaded, o)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	tokens := make(overrideTokens, 0, len(loaded))
+	for _, o := range loaded {
+		token, err := ApplyOverride(o)
+		if err != nil {
+			tokens.Close()
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// overrideTokens removes a batch of overrides.
+type overrideTokens []Closable
+
+func (p overrideTokens) Close() {
+	for i := len(p) - 1; i >= 0; i-- {
+		p[i].Close()
+	}
+}
+
+// envOverrideTarget turns the target part of an override variable name
+// into a type name: "__" is an underscore, and the first other "_" is the
+// "." after the package.
+func envOverrideTarget(name string) string {
+	parts := strings.Split(name, "__")
+	for i, part := range parts {
+		if strings.Contains(part, "_") {
+			parts[i] = strings.Replace(part, "_", ".", 1)
+			break
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// overrideFlag is a flag.Value that applies an override each time it is set.
+type overrideFlag struct {
+	name  string
+	specs []string
+}
+
+func (p *overrideFlag) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(p.specs, " ")
+}
+
+func (p *overrideFlag) Set(spec string) error {
+	o, err := ParseOverride(spec)
+	if err != nil {
+		return err
+	}
+	o.Source = "flag -" + p.name
+	if _, err := ApplyOverride(o); err != nil {
+		return err
+	}
+	p.specs = append(p.specs, o.String())
+	return nil
+}
+
+// OverrideFlag returns a flag.Value that applies an override each time the
+// flag is given, for registering under a name other than OverrideFlagName.
+func OverrideFlag(name string) flag.Value {
+	return &overrideFlag{name: name}
+}
+
+// RegisterOverrideFlag registers the repeatable -godi.bind flag on fs, or
+// flag.CommandLine if fs is nil.
+func RegisterOverrideFlag(fs *flag.FlagSet) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	fs.Var(OverrideFlag(OverrideFlagName), OverrideFlagName,
+		"override a binding, as target=implementor[,cached][,name=registration-name]; may be repeated")
+}
+
+// ActiveOverrides lists the overrides in effect, sorted by target and name.
+func ActiveOverrides() []Override {
+	overrides.Lock()
+	defer overrides.Unlock()
+
+	list := make([]Override, 0, len(overrides.active))
+	for _, a := range overrides.active {
+		list = append(list, *a)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Target != list[j].Target {
+			return list[i].Target < list[j].Target
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// resetOverrides removes every override.
+func resetOverrides() {
+	overrides.Lock()
+	defer overrides.Unlock()
+
+	overrides.context.Reset()
+	overrides.active = nil
+	atomic.StoreInt64(&overrides.count, 0)
+}
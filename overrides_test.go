@@ -0,0 +1,132 @@
+package godi
+
+import (
+	"errors"
+	"flag"
+	"io"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestParseOverride() {
+	o, err := ParseOverride("safari.Animal=safari.Zebra,cached,name=striped")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), Override{Target: "safari.Animal", Implementor: "safari.Zebra", Cached: true, Name: "striped"}, *o)
+	assert.Equal(s.T(), "safari.Animal=safari.Zebra,cached,name=striped", o.String())
+
+	for _, bad := range []string{"", "safari.Animal", "=safari.Zebra", "safari.Animal=", "safari.Animal=safari.Zebra,forever"} {
+		_, err := ParseOverride(bad)
+		assert.NotNil(s.T(), err, bad)
+	}
+}
+
+func (s *GoDiTestSuite) TestEnvOverrides() {
+	s.registerConfigTypes()
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	loaded, err := LoadEnvOverrides([]string{"HOME=/root", "GODI_BIND_godi_I1=godi.T2,cached"})
+	assert.Nil(s.T(), err)
+
+	// overrides win over every scope
+	r1, _ := scope.Resolve((*I1)(nil))
+	r2, _ := Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r1)
+	assert.True(s.T(), r1 == r2)
+
+	all, _ := scope.ResolveAll((*I1)(nil))
+	assert.Equal(s.T(), 1, len(all))
+
+	active := ActiveOverrides()
+	assert.Equal(s.T(), 1, len(active))
+	assert.Equal(s.T(), "environment variable GODI_BIND_godi_I1", active[0].Source)
+
+	_, err = LoadEnvOverrides([]string{"GODI_BIND_godi_I1=godi.Missing", "GODI_BIND_godi_I2=godi.T1"})
+	var unknown *UnknownTypeError
+	var notImplemented *NotImplementedError
+	assert.True(s.T(), errors.As(err, &unknown))
+	assert.True(s.T(), errors.As(err, &notImplemented))
+	assert.Equal(s.T(), 1, len(ActiveOverrides()))
+
+	// nothing is applied if any variable is invalid
+	_, err = LoadEnvOverrides([]string{"GODI_BIND_godi_I1=godi.T1,name=one", "GODI_BIND_godi_I2=godi.T1"})
+	assert.NotNil(s.T(), err)
+	assert.Equal(s.T(), 1, len(ActiveOverrides()))
+
+	loaded.Close()
+	assert.Equal(s.T(), 0, len(ActiveOverrides()))
+	r1, _ = scope.Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r1)
+}
+
+func (s *GoDiTestSuite) TestFlagOverrides() {
+	s.registerConfigTypes()
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterOverrideFlag(fs)
+	err := fs.Parse([]string{"-godi.bind", "godi.I1=godi.T2", "-godi.bind=godi.I1=godi.T1,name=one"})
+	assert.Nil(s.T(), err)
+
+	r, _ := Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r)
+	r, _ = ResolveNamed((*I1)(nil), "one")
+	assert.IsType(s.T(), &T1{}, r)
+
+	active := ActiveOverrides()
+	assert.Equal(s.T(), 2, len(active))
+	assert.Equal(s.T(), "", active[0].Name)
+	assert.Equal(s.T(), "flag -godi.bind", active[0].Source)
+	assert.Equal(s.T(), "godi.I1=godi.T2 godi.I1=godi.T1,name=one", fs.Lookup(OverrideFlagName).Value.String())
+
+	err = fs.Parse([]string{"-godi.bind", "godi.I1=godi.Nope"})
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestEnvOverrideTarget() {
+	assert.Equal(s.T(), "safari.Animal", envOverrideTarget("safari_Animal"))
+	assert.Equal(s.T(), "big_cats.Lion", envOverrideTarget("big__cats_Lion"))
+	assert.Equal(s.T(), "cats.Big_Lion", envOverrideTarget("cats_Big__Lion"))
+	assert.Equal(s.T(), "cats.Lion_King", envOverrideTarget("cats_Lion_King"))
+}
+
+func (s *GoDiTestSuite) TestOverrideDecorated() {
+	s.registerConfigTypes()
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	RegisterDecorator((*I1)(nil), decorateWith("root"))
+
+	_, err := ApplyOverride(&Override{Target: "godi.I1", Implementor: "godi.T2", Cached: true})
+	assert.Nil(s.T(), err)
+
+	// the cached override belongs to the root scope, so it gets root's
+	// decorators but not those of the scope that first resolves it
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterDecorator((*I1)(nil), decorateWith("scope"))
+
+	r1, err := scope.Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	r2, _ := Resolve((*I1)(nil))
+	assert.Equal(s.T(), "t2+root", r1.(I1).F1())
+	assert.True(s.T(), r1 == r2)
+}
+
+func (s *GoDiTestSuite) TestCloseOverride() {
+	s.registerConfigTypes()
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	closer, err := ApplyOverride(&Override{Target: "godi.I1", Implementor: "godi.T2"})
+	assert.Nil(s.T(), err)
+	r, trace, _ := ResolveWithTrace((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r)
+	assert.Equal(s.T(), "overrides", trace.Scope)
+
+	closer.Close()
+	r, _ = Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r)
+	assert.Equal(s.T(), 0, len(ActiveOverrides()))
+}
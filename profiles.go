@@ -0,0 +1,209 @@
+package godi
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// --------
+//
+// Profiles and conditional registrations.  A registration made with
+// InProfile or When is only visible while its condition holds: lookups
+// skip it otherwise, as if it hadn't been made.  This lets one binary wire
+// an in-memory store in tests and a real client in production:
+//
+//	godi.RegisterTypeImplementor((*Store)(nil), MemStore{}, true, nil, godi.InProfile("test"))
+//	godi.RegisterTypeImplementor((*Store)(nil), SQLStore{}, true, nil, godi.InProfile("prod"))
+//
+// The active profiles come from SetProfiles, or if it hasn't been called,
+// from the GODI_PROFILES environment variable; configurations loaded with a
+// "profiles" list activate those too, while they are loaded.
+//
+// --------
+
+// ProfilesEnv is the environment variable holding the comma-separated
+// profiles that are active unless SetProfiles is called.
+const ProfilesEnv = "GODI_PROFILES"
+
+var profileState = struct {
+	sync.RWMutex
+
+	// profiles are the profiles from SetProfiles or the environment.
+	profiles map[string]bool
+
+	// loaded counts the configurations activating each profile.
+	loaded map[string]int
+}{}
+
+// SetProfiles replaces the active profiles, overriding ProfilesEnv.
+// Profiles activated by loaded configurations stay active.  Reset goes back
+// to the environment.
+func SetProfiles(profiles ...string) {
+	profileState.Lock()
+	defer profileState.Unlock()
+
+	profileState.profiles = map[string]bool{}
+	for _, profile := range profiles {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profileState.profiles[profile] = true
+		}
+	}
+}
+
+// ActiveProfiles returns the active profiles, sorted.
+func ActiveProfiles() []string {
+	profileState.RLock()
+	defer profileState.RUnlock()
+
+	var active []string
+	for profile := range profileState.profiles {
+		active = append(active, profile)
+	}
+	for profile := range profileState.loaded {
+		if !profileState.profiles[profile] {
+			active = append(active, profile)
+		}
+	}
+	sort.Strings(active)
+	return active
+}
+
+// ProfileActive returns true if profile is active.
+func ProfileActive(profile string) bool {
+	profileState.RLock()
+	defer profileState.RUnlock()
+	return profileState.profiles[profile] || profileState.loaded[profile] > 0
+}
+
+// resetProfiles drops the profiles set and loaded, and reads ProfilesEnv
+// again.
+func resetProfiles() {
+	SetProfiles(strings.Split(os.Getenv(ProfilesEnv), ",")...)
+
+	profileState.Lock()
+	defer profileState.Unlock()
+	profileState.loaded = nil
+}
+
+func init() {
+	resetProfiles()
+}
+
+// activateProfiles activates profiles until the result is closed.
+func activateProfiles(profiles []string) Closable {
+	profileState.Lock()
+	defer profileState.Unlock()
+
+	if profileState.loaded == nil {
+		profileState.loaded = map[string]int{}
+	}
+	for _, profile := range profiles {
+		profileState.loaded[profile]++
+	}
+	return &profilesToken{profiles: profiles, loaded: profileState.loaded}
+}
+
+type profilesToken struct {
+	profiles []string
+	loaded   map[string]int
+	once     sync.Once
+}
+
+func (p *profilesToken) Close() {
+	p.once.Do(func() {
+		profileState.Lock()
+		defer profileState.Unlock()
+
+		// a no-op if the profiles have been reset since.
+		for _, profile := range p.profiles {
+			if p.loaded[profile]--; p.loaded[profile] <= 0 {
+				delete(p.loaded, profile)
+			}
+		}
+	})
+}
+
+// InProfile makes a registration visible only while one of profiles is
+// active.
+func InProfile(profiles ...string) RegistrationOption {
+	return func(p *typeRegistration) {
+		p.profiles = append(p.profiles, profiles...)
+	}
+}
+
+// When makes a registration visible only while condition returns true.  It
+// is called on every lookup that considers the registration, so it should
+// be cheap, safe to call concurrently, and must not resolve anything.
+func When(condition func() bool) RegistrationOption {
+	return func(p *typeRegistration) {
+		p.conditions = append(p.conditions, condition)
+	}
+}
+
+// conditionalCount counts the registrations made with InProfile or When, so
+// lookups that miss don't look for inactive ones when there are none.
+var conditionalCount int64
+
+// conditional returns true if the registration was made with InProfile or
+// When.
+func (p *typeRegistration) conditional() bool {
+	return len(p.profiles) > 0 || len(p.conditions) > 0
+}
+
+// active returns true if the registration's profiles and conditions allow
+// it to be used.
+func (p *typeRegistration) active() bool {
+	if len(p.profiles) > 0 {
+		found := false
+		for _, profile := range p.profiles {
+			if found = ProfileActive(profile); found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, condition := range p.conditions {
+		if !condition() {
+			return false
+		}
+	}
+	return true
+}
+
+// inactiveRegistrations describes the registrations for typeName and name
+// visible from this scope that are skipped because their condition doesn't
+// hold, as "scope: implementor (condition)".
+func (p *registrationContext) inactiveRegistrations(typeName string, name string) []string {
+	if atomic.LoadInt64(&conditionalCount) == 0 {
+		return nil
+	}
+
+	var inactive []string
+	typeName = formatType(typeName)
+	for _, ctx := range p.lookupChain() {
+		for _, reg := range ctx.allRegistrations() {
+			if reg.targetType.typeName == typeName && reg.name == name && !reg.active() {
+				inactive = append(inactive, ctx.name+": "+reg.implType.typeName+" ("+reg.condition()+")")
+			}
+		}
+	}
+	return inactive
+}
+
+// condition describes the registration's condition for diagnostics, or
+// returns "" if it has none.
+func (p *typeRegistration) condition() string {
+	var parts []string
+	if len(p.profiles) > 0 {
+		parts = append(parts, "profile "+strings.Join(p.profiles, "|"))
+	}
+	if len(p.conditions) > 0 {
+		parts = append(parts, "when")
+	}
+	return strings.Join(parts, ", ")
+}
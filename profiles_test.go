@@ -0,0 +1,175 @@
+package godi
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestInProfile() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil, InProfile("test"))
+	RegisterTypeImplementor((*I1)(nil), T2{}, false, nil, InProfile("prod", "staging"))
+
+	_, err := Resolve((*I1)(nil))
+	var notFound *NotFoundError
+	assert.True(s.T(), errors.As(err, &notFound))
+	assert.Equal(s.T(), []string{"root: godi.T1 (profile test)", "root: godi.T2 (profile prod|staging)"}, notFound.Inactive)
+	assert.True(s.T(), strings.HasSuffix(err.Error(), "; active profiles: none"), err.Error())
+
+	SetProfiles("staging")
+	r, _ := Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r)
+
+	SetProfiles("test")
+	r, _ = Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r)
+	all, _ := ResolveAll((*I1)(nil))
+	assert.Len(s.T(), all, 1)
+	assert.Equal(s.T(), []string{"test"}, ActiveProfiles())
+
+	// inactive registrations aren't verified
+	RegisterTypeImplementor(T3{}, TFail{}, false, nil, InProfile("prod"))
+	RegisterProvider((*I2)(nil), func(T2) I2 { return nil }, false, InProfile("prod"))
+	assert.Nil(s.T(), Verify(true))
+}
+
+func (s *GoDiTestSuite) TestProfilesEnv() {
+	s.T().Setenv(ProfilesEnv, "dev, local")
+	Reset()
+	assert.Equal(s.T(), []string{"dev", "local"}, ActiveProfiles())
+	assert.True(s.T(), ProfileActive("local"))
+
+	SetProfiles()
+	assert.False(s.T(), ProfileActive("local"))
+
+	Reset()
+	assert.True(s.T(), ProfileActive("local"))
+}
+
+func (s *GoDiTestSuite) TestWhen() {
+	enabled := false
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	RegisterTypeImplementor((*I1)(nil), T2{}, false, nil, When(func() bool { return enabled }))
+
+	r, _ := Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T1{}, r)
+
+	_, trace, _ := ResolveWithTrace((*I1)(nil))
+	assert.Equal(s.T(), []string{"root: godi.T2 (when)"}, trace.Inactive)
+	assert.Contains(s.T(), trace.String(), "inactive: root: godi.T2 (when)")
+
+	enabled = true
+	r, _ = Resolve((*I1)(nil))
+	assert.IsType(s.T(), &T2{}, r)
+
+	// conditions and profiles must all hold
+	RegisterTypeImplementor(T2{}, T2{}, false, nil, When(func() bool { return enabled }), InProfile("prod"))
+	_, err := Resolve(T2{})
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	SetProfiles("prod")
+	_, err = Resolve(T2{})
+	assert.Nil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestConditionalCount() {
+	// misses only look for inactive registrations while there are
+	// conditional ones.
+	count := func() int64 { return atomic.LoadInt64(&conditionalCount) }
+	before := count()
+
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+	token, _ := RegisterTypeImplementor((*I1)(nil), T2{}, false, nil, InProfile("prod"))
+	assert.Equal(s.T(), before+1, count())
+	token.Close()
+	token.Close()
+	assert.Equal(s.T(), before, count())
+
+	scope := CreateScope(false)
+	scope.RegisterTypeImplementor((*I1)(nil), T2{}, false, nil, When(func() bool { return false }))
+	scope.RegisterTypeImplementor((*I1)(nil), T1{}, false, nil, InProfile("prod"), Named("prod"))
+	assert.Equal(s.T(), before+2, count())
+
+	_, err := scope.ResolveNamed((*I1)(nil), "missing")
+	assert.NotNil(s.T(), err)
+	var notFound *NotFoundError
+	errors.As(err, &notFound)
+	assert.Nil(s.T(), notFound.Inactive)
+
+	scope.Close()
+	assert.Equal(s.T(), before, count())
+}
+
+func (s *GoDiTestSuite) TestProfilesConfig() {
+	s.registerConfigTypes()
+
+	config := `{
+		"profiles": ["dev"],
+		"bindings": [
+			{"target": "godi.I1", "implementor": "godi.T1", "profile": "dev"},
+			{"target": "godi.T2", "implementor": "godi.T2", "profile": "prod"}
+		]
+	}`
+
+	closer, err := LoadConfig(strings.NewReader(config))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), []string{"dev"}, ActiveProfiles())
+
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.IsType(s.T(), &T1{}, r)
+	_, err = Resolve(T2{})
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+
+	// activating the profile later uses the binding
+	SetProfiles("prod")
+	_, err = Resolve(T2{})
+	assert.Nil(s.T(), err)
+
+	closer.Close()
+	assert.Equal(s.T(), []string{"prod"}, ActiveProfiles())
+}
+
+func (s *GoDiTestSuite) TestProfilesConfigDeferredTypes() {
+	s.registerConfigTypes()
+
+	// godi.Mock only exists in test builds, so it's checked when resolved
+	config := `{
+		"bindings": [
+			{"target": "godi.I1", "implementor": "godi.T1"},
+			{"target": "godi.I1", "implementor": "godi.Mock", "profile": "test"}
+		]
+	}`
+
+	closer, err := LoadConfig(strings.NewReader(config))
+	assert.Nil(s.T(), err)
+	defer closer.Close()
+	r, err := Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.IsType(s.T(), &T1{}, r)
+
+	SetProfiles("test")
+	_, err = Resolve((*I1)(nil))
+	var unknown *UnknownTypeError
+	assert.True(s.T(), errors.As(err, &unknown))
+
+	// an active profile's bindings are checked when loaded
+	_, err = LoadConfig(strings.NewReader(config))
+	assert.True(s.T(), errors.As(err, &unknown))
+}
+
+func (s *GoDiTestSuite) TestProfilesGraph() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil, InProfile("test"))
+
+	g := DependencyGraph()
+	impl := g.Nodes[1]
+	assert.Equal(s.T(), "godi.T1 (profile test) (inactive)", impl.Label)
+	assert.Equal(s.T(), "profile test", impl.Condition)
+	assert.True(s.T(), impl.Inactive)
+
+	SetProfiles("test")
+	impl = DependencyGraph().Nodes[1]
+	assert.Equal(s.T(), "godi.T1 (profile test)", impl.Label)
+	assert.False(s.T(), impl.Inactive)
+}
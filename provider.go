@@ -0,0 +1,136 @@
+package godi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// --------
+//
+// Provider registrations build implementors by calling a function whose
+// parameters are themselves resolved by godi, e.g.:
+//
+// func(db DB, log Logger) (*Service, error)
+//
+// --------
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// validateProvider checks that provider is a function returning a value, or a
+// value and an error, and returns the function value and the type it provides.
+func validateProvider(provider interface{}) (reflect.Value, reflect.Type, error) {
+	fn := reflect.ValueOf(provider)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return reflect.Value{}, nil, fmt.Errorf("Expected a provider function, got %T", provider)
+	}
+
+	ft := fn.Type()
+	if ft.IsVariadic() {
+		return reflect.Value{}, nil, fmt.Errorf("Provider %v can't be variadic", ft)
+	}
+
+	switch {
+	case ft.NumOut() == 1 && ft.Out(0) != errorType:
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+	default:
+		return reflect.Value{}, nil, fmt.Errorf("Provider %v must return a value, or a value and an error", ft)
+	}
+
+	out := ft.Out(0)
+	if out.Kind() == reflect.Ptr {
+		out = out.Elem()
+	}
+	return fn, out, nil
+}
+
+func (p *registrationContext) RegisterProvider(target interface{}, provider interface{}, cached bool, opts ...RegistrationOption) (Closable, error) {
+
+	fn, out, err := validateProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	t := instanceToType(target)
+	tr := &typeRegistration{
+		targetType: newtypeInfo("", &t),
+		implType:   newtypeInfo("", &out),
+		provider:   fn,
+		cached:     cached,
+	}
+
+	if err := ensureProvides(fn.Type().Out(0), t); err != nil {
+		return nil, err
+	}
+
+	return p.register(tr, opts), nil
+}
+
+// ensureProvides checks that the values a provider or decorator returns can be
+// used as a target: they must implement an interface target themselves, not
+// just through a pointer to them, and be the target or a pointer to it when
+// it's concrete.
+func ensureProvides(out reflect.Type, target reflect.Type) error {
+	if target.Kind() == reflect.Interface && out.Implements(target) ||
+		out == target || out == reflect.PtrTo(target) {
+		return nil
+	}
+	return &NotImplementedError{Implementor: typeToString(out), Target: typeToString(target)}
+}
+
+// callProvider resolves each of the provider's parameters from this scope,
+// then calls it.
+func (p *registrationContext) callProvider(state *resolveState, fn reflect.Value) (interface{}, error) {
+	ft := fn.Type()
+	args := make([]reflect.Value, ft.NumIn())
+
+	for i := range args {
+		arg, err := p.resolveValue(state, ft.In(i), "")
+		if err != nil {
+			return nil, fmt.Errorf("Resolving parameter %d (%v) of provider %v: %w", i, ft.In(i), ft, err)
+		}
+		args[i] = arg
+	}
+
+	out := fn.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+
+	result := out[0]
+	if isNilValue(result) {
+		return nil, errors.New("Provider " + ft.String() + " returned nil")
+	}
+	return result.Interface(), nil
+}
+
+// resolveValue resolves t from this scope as part of state, and adapts the
+// result so it can be assigned to a value of type t.
+func (p *registrationContext) resolveValue(state *resolveState, t reflect.Type, name string) (reflect.Value, error) {
+	raw, err := p.resolveCore(state, t, name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return assignableValue(raw, t)
+}
+
+// assignableValue converts raw to a value assignable to t.  Type registrations
+// are created as pointers, so they are dereferenced when t is the value type.
+func assignableValue(raw interface{}, t reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(raw)
+	switch {
+	case v.Type().AssignableTo(t):
+		return v, nil
+	case v.Kind() == reflect.Ptr && v.Type().Elem().AssignableTo(t):
+		return v.Elem(), nil
+	}
+	return reflect.Value{}, fmt.Errorf("Resolved %v, which is not assignable to %v", v.Type(), t)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
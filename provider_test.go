@@ -0,0 +1,124 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	I3 interface {
+		F3() string
+	}
+	TProvided struct {
+		dep   I1
+		count int
+	}
+)
+
+func (p *TProvided) F3() string {
+	return p.dep.F1()
+}
+
+func (s *GoDiTestSuite) TestProvider() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "dep"})
+
+	calls := 0
+	_, err := RegisterProvider((*I3)(nil), func(dep I1) (*TProvided, error) {
+		calls++
+		return &TProvided{dep: dep, count: calls}, nil
+	}, false)
+	assert.Nil(s.T(), err)
+
+	r1, err := Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "dep", r1.(I3).F3())
+
+	r2, _ := Resolve((*I3)(nil))
+	assert.Equal(s.T(), 2, r2.(*TProvided).count)
+}
+
+func (s *GoDiTestSuite) TestProviderCached() {
+	RegisterTypeImplementor((*I1)(nil), T3{}, false, nil)
+
+	calls := 0
+	RegisterProvider((*I3)(nil), func(dep I1) *TProvided {
+		calls++
+		return &TProvided{dep: dep}
+	}, true)
+
+	r1, _ := Resolve((*I3)(nil))
+	r2, _ := Resolve((*I3)(nil))
+	assert.Equal(s.T(), r1, r2)
+	assert.Equal(s.T(), 1, calls)
+
+	// parameters are initialized like any other resolved instance
+	assert.Equal(s.T(), "42", r1.(I3).F3())
+}
+
+func (s *GoDiTestSuite) TestProviderScope() {
+	RegisterProvider((*I3)(nil), func(dep I1) *TProvided {
+		return &TProvided{dep: dep}
+	}, false)
+
+	// the parameter is resolved from the scope resolved against, not the
+	// scope the provider was registered in.
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "scoped"})
+
+	r1, err := scope.Resolve((*I3)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "scoped", r1.(I3).F3())
+
+	_, err = Resolve((*I3)(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestProviderError() {
+	fail := errors.New("no database")
+	RegisterProvider((*I3)(nil), func() (*TProvided, error) {
+		return nil, fail
+	}, true)
+
+	r1, err := Resolve((*I3)(nil))
+	assert.Nil(s.T(), r1)
+	assert.True(s.T(), errors.Is(err, fail))
+
+	var initErr *InitError
+	assert.True(s.T(), errors.As(err, &initErr))
+}
+
+func (s *GoDiTestSuite) TestProviderInvalid() {
+	_, err := RegisterProvider((*I3)(nil), "not a func", false)
+	assert.NotNil(s.T(), err)
+
+	_, err = RegisterProvider((*I3)(nil), func() error { return nil }, false)
+	assert.NotNil(s.T(), err)
+
+	_, err = RegisterProvider((*I3)(nil), func() (*T1, error) { return nil, nil }, false)
+	assert.NotNil(s.T(), err)
+
+	_, err = RegisterProvider((*I3)(nil), func(...I1) *TProvided { return nil }, false)
+	assert.NotNil(s.T(), err)
+}
+
+type TValueOnly struct{}
+
+func (p *TValueOnly) F3() string {
+	return "pointer"
+}
+
+func (s *GoDiTestSuite) TestProviderResultImplements() {
+	// only *TValueOnly implements I3, so a provider returning the value
+	// can't provide it.
+	_, err := RegisterProvider((*I3)(nil), func() TValueOnly { return TValueOnly{} }, false)
+	var notImplemented *NotImplementedError
+	assert.True(s.T(), errors.As(err, &notImplemented))
+
+	_, err = RegisterProvider((*I3)(nil), func() *TValueOnly { return &TValueOnly{} }, false)
+	assert.Nil(s.T(), err)
+
+	_, err = RegisterDecorator((*I3)(nil), func(i I3) TValueOnly { return TValueOnly{} })
+	assert.NotNil(s.T(), err)
+}
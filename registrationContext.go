@@ -2,10 +2,13 @@ package godi
 
 import (
 	"container/list"
-	"errors"
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 /// ---------------------------
@@ -19,40 +22,105 @@ import (
 type closeHandler func()
 
 type registrationContext struct {
+	name          string
 	parent        *registrationContext
 	registrations map[string]*list.List
 	initializers  *list.List
 	onclose       closeHandler
+	scoped        map[int]*instanceSlot
+	decorators    map[string][]*decorator
+	hooks         map[HookPhase][]*hook
+	values        []*valueSource
+	lifecycle     lifecycle
 	rwlock        sync.RWMutex
+
+	// modules are the modules installed in this scope, see Install.
+	// moduleLock serializes installing and removing them.
+	modules    map[*Module]*installedModule
+	moduleLock sync.Mutex
 }
 
 var _ RegistrationContext = &registrationContext{}
 
+var scopeCounter int64
+
 func newregistrationContext(parent *registrationContext) *registrationContext {
 	p := &registrationContext{
+		name:          "root",
 		registrations: map[string]*list.List{},
 		initializers:  list.New(),
+		scoped:        map[int]*instanceSlot{},
+		decorators:    map[string][]*decorator{},
+		hooks:         map[HookPhase][]*hook{},
 	}
 	if parent != nil {
+		p.name = fmt.Sprintf("scope-%d", atomic.AddInt64(&scopeCounter, 1))
 		p.parent = parent
 	}
 	return p
 }
 
+// getParent returns the parent scope, or nil for the root or a closed scope.
+func (p *registrationContext) getParent() *registrationContext {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+	return p.parent
+}
+
 //
 // Initializer stuff
 //
 
 func (p *registrationContext) RegisterInstanceInitializer(initializer InstanceInitializer) error {
+	p.rwlock.Lock()
+	defer p.rwlock.Unlock()
+
 	p.initializers.PushFront(initializer)
 	return nil
 }
 
+// addInitializer adds an instance initializer, returning a Closable that
+// removes it again.
+func (p *registrationContext) addInitializer(initializer InstanceInitializer) Closable {
+	p.rwlock.Lock()
+	defer p.rwlock.Unlock()
+
+	return &initializerToken{context: p, element: p.initializers.PushFront(initializer)}
+}
+
+type initializerToken struct {
+	context *registrationContext
+	element *list.Element
+}
+
+func (p *initializerToken) Close() {
+	p.context.rwlock.Lock()
+	defer p.context.rwlock.Unlock()
+
+	// a no-op if the element was already removed, or the scope reset.
+	p.context.initializers.Remove(p.element)
+}
+
+// getInitializers returns a snapshot of this scope's instance initializers.
+func (p *registrationContext) getInitializers() []InstanceInitializer {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+
+	var inits []InstanceInitializer
+	for e := p.initializers.Front(); e != nil; e = e.Next() {
+		if init, ok := e.Value.(InstanceInitializer); ok && init != nil {
+			inits = append(inits, init)
+		}
+	}
+	return inits
+}
+
 var initializableType, _ = ExtractType((*Initializable)(nil))
 
-func (p *registrationContext) initializeInstance(instance interface{}, typeReg *typeRegistration) (interface{}, error) {
+func (p *registrationContext) initializeInstance(state *resolveState, instance interface{}, typeReg *typeRegistration, constructed bool) (interface{}, error) {
 
 	// order of initialization is:
+	// 0. Value fields
 	// 1. Init callback
 	// 2. Initialize ctor
 	// 3. InstanceInitializer
@@ -60,36 +128,65 @@ func (p *registrationContext) initializeInstance(instance interface{}, typeReg *
 	var err error
 	callInitializers := true
 
+	if planHasValues(instance) {
+		start := time.Now()
+		err = p.injectValues(instance, constructed)
+		traceStep(state, "inject values", start, err)
+		if err != nil {
+			return nil, newInitError(typeReg, err)
+		}
+	}
+
 	if typeReg.initializer != nil {
+		start := time.Now()
 		callInitializers, err = typeReg.initializer(instance)
+		traceStep(state, "InitializeCallback", start, err)
 		if err != nil && !callInitializers {
-			// if there is no other option for initializing, we should panic and stop the whole thing
-			panic(fmt.Sprintf("Error with initializer for %s: %s", typeReg.implType.typeName, err.Error()))
+			// if there is no other option for initializing, stop the whole thing
+			return nil, newInitError(typeReg, err)
+		}
+		if err == nil {
+			if instance, err = p.afterInitializer(typeReg, "InitializeCallback", start, instance); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	if callInitializers {
 		if init, ok := instance.(Initializable); ok {
-			if initErr := init.GodiInit(); initErr != nil {
-				// if the built-in intializer fails, we are in big trouble...panic!
-				//
-
-				panic(fmt.Sprintf("Error initializing '%s' (registered for target '%s'): %v", typeReg.implType.typeName, typeReg.targetType.typeName, initErr))
+			start := time.Now()
+			initErr := init.GodiInit()
+			traceStep(state, "GodiInit", start, initErr)
+			if initErr != nil {
+				return nil, newInitError(typeReg, initErr)
+			}
+			if instance, err = p.afterInitializer(typeReg, "GodiInit", start, instance); err != nil {
+				return nil, err
 			}
 		}
 
-		if callInitializers {
-			l := p.initializers
-			for e := l.Front(); e != nil; e = e.Next() {
-				init := e.Value.(InstanceInitializer)
-				if init != nil && init.CanInitialize(instance, typeReg.implType.typeName) {
-					return init.Initialize(instance, typeReg.implType.typeName)
+		// the first initializer in this scope or its parents that can
+		// initialize the instance wins.
+		for ctx := p; ctx != nil; ctx = ctx.getParent() {
+			for _, init := range ctx.getInitializers() {
+				if init.CanInitialize(instance, typeReg.implType.typeName) {
+					start := time.Now()
+					var initialized interface{}
+					var initErr error
+					if scoped, ok := init.(ScopeInitializer); ok {
+						scope := &resolvingScope{registrationContext: p, state: state}
+						initialized, initErr = scoped.InitializeInScope(scope, instance, typeReg.implType.typeName)
+					} else {
+						initialized, initErr = init.Initialize(instance, typeReg.implType.typeName)
+					}
+					step := fmt.Sprintf("InstanceInitializer %T", init)
+					traceStep(state, step, start, initErr)
+					if initErr != nil {
+						return nil, newInitError(typeReg, initErr)
+					}
+					return p.afterInitializer(typeReg, step, start, initialized)
 				}
 			}
-
-			if p.parent != nil {
-				return p.parent.initializeInstance(instance, typeReg)
-			}
 		}
 	}
 	return instance, nil
@@ -112,19 +209,106 @@ func (p *registrationContext) addRegistration(reg *typeRegistration) {
 	}
 
 	l.PushFront(reg)
+	if reg.conditional() {
+		atomic.AddInt64(&conditionalCount, 1)
+	}
 }
 
-func (p *registrationContext) findRegistration(typeName string) *typeRegistration {
+// findRegistration returns the latest active registration for typeName
+// with the given registration name ("" for unnamed registrations), either a
+// regular or a default one.  Registrations whose profiles or conditions
+// don't hold are skipped.
+func (p *registrationContext) findRegistration(typeName string, name string, defaults bool) *typeRegistration {
 	p.rwlock.RLock()
 	defer p.rwlock.RUnlock()
 
 	typeName = formatType(typeName)
 	l := p.registrations[typeName]
-	if l == nil || l.Len() == 0 {
+	if l == nil {
 		return nil
 	}
 
-	return l.Front().Value.(*typeRegistration)
+	for e := l.Front(); e != nil; e = e.Next() {
+		if reg := e.Value.(*typeRegistration); reg.name == name && reg.isDefault == defaults && reg.active() {
+			return reg
+		}
+	}
+	return nil
+}
+
+// lookupChain returns the scopes consulted when resolving against this
+// scope, in order: the override scope if any overrides are active, this
+// scope, and its parents.
+func (p *registrationContext) lookupChain() []*registrationContext {
+	var chain []*registrationContext
+	if overrides := overrideContext(); overrides != nil {
+		chain = append(chain, overrides)
+	}
+	for ctx := p; ctx != nil; ctx = ctx.getParent() {
+		chain = append(chain, ctx)
+	}
+	return chain
+}
+
+// lookupRegistration walks the lookup chain from this scope to find the
+// registration for typeName, returning it and the names of the scopes searched.
+// Default registrations are only considered if no scope has a regular one.
+func (p *registrationContext) lookupRegistration(typeName string, name string) (*typeRegistration, []string) {
+	var searched []string
+	chain := p.lookupChain()
+	for _, ctx := range chain {
+		searched = append(searched, ctx.name)
+		if reg := ctx.findRegistration(typeName, name, false); reg != nil {
+			return reg, searched
+		}
+	}
+	for _, ctx := range chain {
+		if reg := ctx.findRegistration(typeName, name, true); reg != nil {
+			return reg, searched
+		}
+	}
+	return nil, searched
+}
+
+// allRegistrations returns every registration in this scope, in the order
+// they were made.
+func (p *registrationContext) allRegistrations() []*typeRegistration {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+
+	var regs []*typeRegistration
+	for _, l := range p.registrations {
+		for e := l.Front(); e != nil; e = e.Next() {
+			regs = append(regs, e.Value.(*typeRegistration))
+		}
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].id < regs[j].id })
+	return regs
+}
+
+// findAllRegistrations returns the active registrations for typeName in
+// this scope, oldest first, and whether any of them shadow the parent scopes.
+func (p *registrationContext) findAllRegistrations(typeName string) ([]*typeRegistration, bool) {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+
+	typeName = formatType(typeName)
+	l := p.registrations[typeName]
+	if l == nil {
+		return nil, false
+	}
+
+	shadow := false
+	regs := make([]*typeRegistration, 0, l.Len())
+	for e := l.Back(); e != nil; e = e.Prev() {
+		reg := e.Value.(*typeRegistration)
+		if !reg.active() {
+			continue
+		}
+		regs = append(regs, reg)
+		shadow = shadow || reg.shadowParents
+	}
+	return regs, shadow
 }
 
 func (p *registrationContext) removeRegistration(reg *typeRegistration) bool {
@@ -145,6 +329,9 @@ func (p *registrationContext) removeRegistration(reg *typeRegistration) bool {
 		r := e.Value.(*typeRegistration)
 		if reg.id == r.id {
 			l.Remove(e)
+			if r.conditional() {
+				atomic.AddInt64(&conditionalCount, -1)
+			}
 			return true
 		}
 	}
@@ -155,92 +342,323 @@ func (p *registrationContext) removeRegistration(reg *typeRegistration) bool {
 // Registration Stuff
 //
 
-func (p *registrationContext) RegisterByName(target string, implmentor string, cached bool) Closable {
+// register assigns the registration an id, applies its options and adds it
+// to this scope.
+func (p *registrationContext) register(tr *typeRegistration, opts []RegistrationOption) *RegistrationToken {
+	tr.id = int(atomic.AddInt64(&registrationCounter, 1))
+	tr.scope = p
+	for _, opt := range opts {
+		opt(tr)
+	}
+
+	p.addRegistration(tr)
+	return &RegistrationToken{context: p, registration: tr}
+}
+
+func (p *registrationContext) RegisterByName(target string, implmentor string, cached bool, opts ...RegistrationOption) Closable {
 
-	registrationCounter++
 	tr := &typeRegistration{
 		targetType: newtypeInfo(target, nil),
 		implType:   newtypeInfo(implmentor, nil),
 		cached:     cached,
-		id:         registrationCounter,
 	}
 
-	p.addRegistration(tr)
-	return &RegistrationToken{context: p, registration: tr}
+	return p.register(tr, opts)
 }
 
-func (p *registrationContext) RegisterInstanceImplementor(target interface{}, instance interface{}) (Closable, error) {
+func (p *registrationContext) RegisterInstanceImplementor(target interface{}, instance interface{}, opts ...RegistrationOption) (Closable, error) {
 	t := instanceToType(target)
 
 	rt := instanceToType(instance)
 
-	registrationCounter++
 	tr := &typeRegistration{
 		targetType: newtypeInfo("", &t),
 		implType:   newtypeInfo("", &rt),
-		instance:   instance,
+		value:      instance,
+		isInstance: true,
 		cached:     true,
-		id:         registrationCounter,
 	}
 
 	if err := tr.ensureImplementor(rt, t); err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
-	p.addRegistration(tr)
-	return &RegistrationToken{context: p, registration: tr}, nil
+	return p.register(tr, opts), nil
 }
 
-func (p *registrationContext) RegisterTypeImplementor(target interface{}, impl interface{}, cached bool, init InitializeCallback) (Closable, error) {
+func (p *registrationContext) RegisterTypeImplementor(target interface{}, impl interface{}, cached bool, init InitializeCallback, opts ...RegistrationOption) (Closable, error) {
 
 	t := instanceToType(target)
 	implementor := instanceToType(impl)
-	registrationCounter++
 	tr := &typeRegistration{
 		targetType:  newtypeInfo("", &t),
 		implType:    newtypeInfo("", &implementor),
 		initializer: init,
 		cached:      cached,
-		id:          registrationCounter,
 	}
 
 	if err := tr.ensureImplementor(implementor, t); err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
-	p.addRegistration(tr)
-	return &RegistrationToken{context: p, registration: tr}, nil
+	return p.register(tr, opts), nil
 }
 
 func (p *registrationContext) Resolve(target interface{}) (interface{}, error) {
 	t := instanceToType(target)
-	return p.resolveCore(t)
+	return p.resolveCore(nil, t, "")
 }
 
-func (p *registrationContext) resolveCore(t reflect.Type) (interface{}, error) {
-	name := typeToString(t)
+// ResolveNamed returns an instance of the implementor registered for target
+// with the given name, falling through to parent scopes like Resolve.
+func (p *registrationContext) ResolveNamed(target interface{}, name string) (interface{}, error) {
+	t := instanceToType(target)
+	return p.resolveCore(nil, t, name)
+}
+
+// ResolveAll returns an instance of every implementor registered for target in
+// this scope and its parents, named or not.  Parent scopes come first, and within a scope
+// registrations are in the order they were made.  A registration made with
+// ShadowParents hides the parent scopes' registrations, and overrides hide
+// every scope's.  If nothing is registered, the result is empty rather than
+// an error.
+func (p *registrationContext) ResolveAll(target interface{}) ([]interface{}, error) {
+	state := newResolveState()
+	defer state.leave()
+	return p.resolveAll(state, target)
+}
+
+func (p *registrationContext) resolveAll(state *resolveState, target interface{}) ([]interface{}, error) {
+	name := typeToString(instanceToType(target))
+
+	event := &ResolveEvent{Target: name}
+	if err := p.runHooks(HookBeforeLookup, event); err != nil {
+		instance, err := p.onError(event, err)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{instance}, nil
+	}
+	if event.Instance != nil {
+		return []interface{}{event.Instance}, nil
+	}
+
+	// collect innermost first, then reverse so parents come first.
+	var scopes [][]*typeRegistration
+	for _, ctx := range p.lookupChain() {
+		regs, shadow := ctx.findAllRegistrations(name)
+		scopes = append(scopes, regs)
+		if shadow || (ctx == overrideContext() && len(regs) > 0) {
+			break
+		}
+	}
+
+	// defaults are only used if there are no regular registrations.
+	defaults := true
+	for _, regs := range scopes {
+		for _, reg := range regs {
+			defaults = defaults && reg.isDefault
+		}
+	}
 
-	reg := p.findRegistration(name)
+	var all []interface{}
+	for i := len(scopes) - 1; i >= 0; i-- {
+		for _, reg := range scopes[i] {
+			if reg.isDefault != defaults {
+				continue
+			}
+			start := time.Now()
+			trace := beginTrace(state, reg.targetType.typeName, reg.name)
+			event := &ResolveEvent{Target: name, Name: reg.name, Implementor: reg.implType.typeName}
+			instance, err := p.resolveChosen(state, event, reg, trace)
+			if err != nil {
+				instance, err = p.onError(event, err)
+			}
+			endTrace(state, trace, start, err)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, instance)
+		}
+	}
+	return all, nil
+}
 
-	if reg == nil && p.parent != nil {
-		return p.parent.Resolve(t)
+// resolveCore resolves t as part of the resolve state is in, or as a new
+// top-level resolve if state is nil.
+func (p *registrationContext) resolveCore(state *resolveState, t reflect.Type, regName string) (interface{}, error) {
+	if state == nil {
+		state = newResolveState()
+		defer state.leave()
 	}
 
-	if reg != nil {
-		raw, created, err := reg.realize()
+	// Lazy and Provider are bound to this scope rather than resolved.
+	if v, ok := p.bindDeferred(state, t, regName); ok {
+		return v.Interface(), nil
+	}
+	if v, ok, err := p.resolveOptional(state, t, regName); ok {
 		if err != nil {
 			return nil, err
 		}
-		if created {
-			return p.initializeInstance(raw, reg)
+		return v.Interface(), nil
+	}
+
+	name := typeToString(t)
+
+	start := time.Now()
+	trace := beginTrace(state, name, regName)
+
+	event := &ResolveEvent{Target: name, Name: regName}
+	instance, err := p.lookupAndResolve(state, event, trace)
+	if err != nil {
+		instance, err = p.onError(event, err)
+	}
+
+	endTrace(state, trace, start, err)
+	return instance, err
+}
+
+// lookupAndResolve finds the registration for event's target in this scope
+// or a parent, and resolves it against this scope.  Hooks may veto the
+// resolve or supply the instance instead.
+func (p *registrationContext) lookupAndResolve(state *resolveState, event *ResolveEvent, trace *ResolveTrace) (interface{}, error) {
+	if err := p.runHooks(HookBeforeLookup, event); err != nil || event.Instance != nil {
+		return event.Instance, err
+	}
+
+	reg, searched := p.lookupRegistration(event.Target, event.Name)
+	if trace != nil || reg == nil {
+		inactive, profiles := p.inactiveRegistrations(event.Target, event.Name), ActiveProfiles()
+		if trace != nil {
+			trace.ScopesSearched = searched
+			trace.Inactive = inactive
+			trace.Profiles = profiles
+		}
+		if reg == nil {
+			return nil, &NotFoundError{
+				TypeName: event.Target,
+				Name:     event.Name,
+				Scopes:   searched,
+				Inactive: inactive,
+				Profiles: profiles,
+			}
+		}
+	}
+
+	traceShadowed(trace, p, reg)
+	event.Implementor = reg.implType.typeName
+	return p.resolveChosen(state, event, reg, trace)
+}
+
+// resolveChosen runs the HookRegistrationChosen hooks for reg, which may
+// veto the resolve or supply the instance, then resolves it against this
+// scope.
+func (p *registrationContext) resolveChosen(state *resolveState, event *ResolveEvent, reg *typeRegistration, trace *ResolveTrace) (interface{}, error) {
+	if err := p.runHooks(HookRegistrationChosen, event); err != nil || event.Instance != nil {
+		return event.Instance, err
+	}
+
+	traceRegistration(trace, reg)
+	return p.resolveRegistration(state, reg, trace)
+}
+
+// resolveRegistration returns the instance for reg, creating and
+// initializing it against this scope if needed.  trace, if not nil, records
+// whether a cached instance was used.
+func (p *registrationContext) resolveRegistration(state *resolveState, reg *typeRegistration, trace *ResolveTrace) (interface{}, error) {
+	state, err := state.enter(reg, trace)
+	if err != nil {
+		return nil, err
+	}
+	defer state.leave()
+
+	created := false
+	var instance interface{}
+	if reg.scoped {
+		instance, err = p.scopedInstance(reg).realize(state, func() (interface{}, error) {
+			created = true
+			return p.createInstance(state, reg)
+		})
+	} else {
+		// cached instances are shared by every scope, so they are created
+		// against the scope they belong to rather than this one, which
+		// may be closed while they are still in use.
+		scope := p
+		if reg.cached {
+			scope = reg.home()
+		}
+		instance, err = reg.realize(state, func() (interface{}, error) {
+			created = true
+			return scope.createInstance(state, reg)
+		})
+	}
+
+	if trace != nil {
+		trace.CacheHit = err == nil && !created
+	}
+	return instance, err
+}
+
+// createInstance creates, initializes and decorates a new instance for reg
+// against this scope.  Cached and scoped instances have their lifecycle
+// managed by this scope.  The value given to an instance registration is
+// only decorated.
+func (p *registrationContext) createInstance(state *resolveState, reg *typeRegistration) (interface{}, error) {
+	done := state.creatingOn()
+	defer done()
+
+	instance := reg.value
+	if !reg.isInstance {
+		var err error
+		if instance, err = p.buildInstance(state, reg); err != nil {
+			return nil, err
+		}
+		if reg.cached || reg.scoped {
+			if err := p.manage(reg, instance); err != nil {
+				return nil, err
+			}
 		}
-		return raw, nil
 	}
-	return nil, errors.New(ErrorRegistrationNotFound)
+	return p.decorate(state, reg, instance)
 }
 
+// buildInstance constructs an instance for reg, injects its fields and
+// initializes it.
+func (p *registrationContext) buildInstance(state *resolveState, reg *typeRegistration) (interface{}, error) {
+	step := "construct"
+	if reg.provider.IsValid() {
+		step = "provider"
+	}
+	start := time.Now()
+	raw, err := reg.construct(state, p)
+	traceStep(state, step, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// unexported fields are only injected into instances godi constructed.
+	constructed := !reg.provider.IsValid()
+
+	start = time.Now()
+	err = p.injectFields(state, raw, constructed)
+	traceStep(state, "inject fields", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	created := raw
+	if raw, err = p.afterCreate(reg, raw); err != nil {
+		return nil, err
+	}
+	return p.initializeInstance(state, raw, reg, constructed && raw == created)
+}
+
+// Close stops the instances this scope manages, see Stop, and removes all
+// of its registrations.
 func (p *registrationContext) Close() {
 
+	p.Stop(context.Background())
+
 	p.rwlock.Lock()
 	if p.registrations != nil {
 
@@ -256,8 +674,12 @@ func (p *registrationContext) Close() {
 	p.Reset()
 }
 
-func (p *registrationContext) createScopeCore(onclose func()) *registrationContext {
+// createScopeCore creates a child scope, named name unless it's empty.
+func (p *registrationContext) createScopeCore(name string, onclose func()) *registrationContext {
 	ctx := newregistrationContext(p)
+	if name != "" {
+		ctx.name = name
+	}
 	if onclose != nil {
 		ctx.onclose = onclose
 	}
@@ -270,12 +692,42 @@ func (p *registrationContext) CreateScope() RegistrationContext {
 	return rc
 }
 
+// CreateNamedScope creates a child scope like CreateScope, with a name of
+// its own rather than a generated one.  Configuration bindings can name it
+// as their scope (see ConfigBinding), and traces, graphs and errors show it.
+func (p *registrationContext) CreateNamedScope(name string) RegistrationContext {
+	return p.createScopeCore(name, nil)
+}
+
 func (p *registrationContext) Reset() {
+	p.moduleLock.Lock()
+	p.modules = nil
+	p.moduleLock.Unlock()
+
 	p.rwlock.Lock()
 	defer p.rwlock.Unlock()
 
+	for _, l := range p.registrations {
+		for e := l.Front(); e != nil; e = e.Next() {
+			if e.Value.(*typeRegistration).conditional() {
+				atomic.AddInt64(&conditionalCount, -1)
+			}
+		}
+	}
 	p.registrations = make(map[string]*list.List)
 	p.initializers = list.New()
+	p.scoped = make(map[int]*instanceSlot)
+	p.decorators = make(map[string][]*decorator)
+	for _, registered := range p.hooks {
+		atomic.AddInt64(&hookCount, -int64(len(registered)))
+	}
+	p.hooks = make(map[HookPhase][]*hook)
+	p.values = nil
+
+	p.lifecycle.Lock()
+	p.lifecycle.managed = nil
+	p.lifecycle.started = false
+	p.lifecycle.Unlock()
 }
 
 /// ----------------
@@ -0,0 +1,318 @@
+package godi
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// --------
+//
+// resolveState is threaded through a resolve and every resolve made while
+// creating its instances: provider and decorator parameters, injected
+// fields, Lazy and Provider values got while it is running, and instance
+// initializers that resolve through the scope they are given (see
+// ScopeInitializer).  Each registration being resolved adds a state to the
+// chain, so re-entering a registration that is already in the chain is a
+// dependency cycle.
+//
+// Cached and scoped instances are created by one resolve at a time, and
+// other resolves wait for it to finish.  A resolve that would wait for a
+// creation that is itself waiting, directly or through other resolves, on
+// an instance the first one is creating is a cycle too, and is reported
+// rather than deadlocking.
+//
+// Providers, decorators and initializers are user code run while an
+// instance is created, and may resolve through the package-level functions
+// or a scope they captured instead of the scope they are given.  Those
+// resolves can't be handed the state, so the state creating an instance is
+// also recorded against its goroutine, and a top-level resolve on that
+// goroutine continues the chain rather than starting a new one.  Goroutines
+// are only identified while instances are being created.
+//
+// --------
+
+type resolveState struct {
+	parent *resolveState
+
+	// reg is the registration being resolved, or nil at the top of the chain.
+	reg *typeRegistration
+
+	// trace is the open trace that resolves made from this state nest
+	// under, if tracing.
+	trace *ResolveTrace
+
+	// resolution is shared by the whole chain.
+	resolution *resolution
+
+	// capturing is set on the state started by ResolveWithTrace, which gets
+	// its top-level trace from captured.
+	capturing bool
+	captured  *ResolveTrace
+
+	// done is set once the resolve of reg has returned.
+	done int32
+}
+
+// resolution is a top-level resolve, and the resolves its user code made
+// through the package-level functions.
+type resolution struct {
+	// waiting is the state blocked on another resolution's creation of
+	// slot.  Both are guarded by waits.
+	waiting *resolveState
+	slot    *instanceSlot
+}
+
+// waits guards the graph of resolutions waiting for each other, see
+// instanceSlot.
+var waits sync.Mutex
+
+// CycleError is returned when resolving a type depends, directly or
+// indirectly, on itself.
+type CycleError struct {
+	// Path lists the targets being resolved, ending with the target that
+	// closed the cycle.
+	Path []string
+}
+
+func (p *CycleError) Error() string {
+	return "Dependency cycle detected: " + strings.Join(p.Path, " -> ")
+}
+
+// creating maps the goroutines creating instances to the state creating
+// them, and creatingCount is the number of instances being created.
+var (
+	creating      sync.Map
+	creatingCount int64
+)
+
+// newResolveState starts the chain for a top-level resolve, or continues
+// the one creating an instance on this goroutine.  leave must be called when
+// it returns.
+func newResolveState() *resolveState {
+	if atomic.LoadInt64(&creatingCount) > 0 {
+		if running, ok := creating.Load(goroutineID()); ok {
+			if s := running.(*resolveState).active(); s != nil {
+				return &resolveState{parent: s, trace: s.trace, resolution: s.resolution}
+			}
+		}
+	}
+	return &resolveState{resolution: &resolution{}}
+}
+
+// creatingOn records that this state is creating an instance on the calling
+// goroutine, and returns a function that restores the goroutine's previous
+// state once it's done.
+func (p *resolveState) creatingOn() func() {
+	id := goroutineID()
+	previous, _ := creating.Load(id)
+	creating.Store(id, p)
+	atomic.AddInt64(&creatingCount, 1)
+
+	return func() {
+		atomic.AddInt64(&creatingCount, -1)
+		if previous != nil {
+			creating.Store(id, previous)
+		} else {
+			creating.Delete(id)
+		}
+	}
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the id of the calling goroutine, parsed from the
+// header of its stack trace ("goroutine 123 [running]:").
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// enter returns the state for resolving reg as part of this one, nesting
+// under trace if it isn't nil, or a *CycleError if reg is already being
+// resolved in the chain.
+func (p *resolveState) enter(reg *typeRegistration, trace *ResolveTrace) (*resolveState, error) {
+	for s := p; s != nil; s = s.parent {
+		if s.reg != nil && s.reg.id == reg.id {
+			return nil, &CycleError{Path: append(p.path(nil), reg.String())}
+		}
+	}
+	if trace == nil {
+		trace = p.trace
+	}
+	return &resolveState{parent: p, reg: reg, trace: trace, resolution: p.resolution}, nil
+}
+
+// leave marks the state's resolve as finished.
+func (p *resolveState) leave() {
+	atomic.StoreInt32(&p.done, 1)
+}
+
+// active returns the state if its resolve is still running, or nil.  Lazy
+// and Provider values, and scopes given to initializers, continue the
+// resolve they were bound in while it runs, so that cycles through them are
+// seen, and start new ones after that.
+func (p *resolveState) active() *resolveState {
+	if p == nil || atomic.LoadInt32(&p.done) != 0 {
+		return nil
+	}
+	return p
+}
+
+// path returns the registrations being resolved below ancestor, down to
+// this state, outermost first.  A nil ancestor returns the whole chain.
+func (p *resolveState) path(ancestor *resolveState) []string {
+	var path []string
+	for s := p; s != nil && s != ancestor; s = s.parent {
+		if s.reg != nil {
+			path = append(path, s.reg.String())
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// waitFor records that this state is about to wait for owner to finish
+// creating slot, or returns a *CycleError if owner's resolution is waiting,
+// directly or through others, on this state's resolution.  It must be
+// called with waits held.
+func (p *resolveState) waitFor(slot *instanceSlot, owner *resolveState) error {
+	path := p.path(nil)
+	if owner.resolution == p.resolution {
+		if owner.reg != nil {
+			path = append(path, owner.reg.String())
+		}
+		return &CycleError{Path: path}
+	}
+	for owner != nil {
+		if owner.resolution == p.resolution {
+			return &CycleError{Path: path}
+		}
+		waiting := owner.resolution.waiting
+		if waiting == nil {
+			break
+		}
+		path = append(path, waiting.path(owner)...)
+		owner = waiting.resolution.slot.creating
+	}
+
+	p.resolution.waiting, p.resolution.slot = p, slot
+	return nil
+}
+
+// stopWaiting records that this state's resolution is no longer waiting.
+func (p *resolveState) stopWaiting() {
+	waits.Lock()
+	defer waits.Unlock()
+	p.resolution.waiting, p.resolution.slot = nil, nil
+}
+
+// instanceSlot holds a cached or scoped instance.  Only one resolution
+// creates it at a time, and others wait for that to finish, then use the
+// instance or, if creating it failed, try again themselves.
+type instanceSlot struct {
+	lock     sync.Mutex
+	instance interface{}
+
+	// creating is the state creating the instance, and done is closed when
+	// it finishes.  They are only changed with both lock and waits held.
+	creating *resolveState
+	done     chan struct{}
+}
+
+func (p *instanceSlot) get() interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.instance
+}
+
+// realize returns the slot's instance, calling create to build it on state
+// if there isn't one.  create is called at most once at a time, and its
+// result is kept once it succeeds.
+func (p *instanceSlot) realize(state *resolveState, create func() (interface{}, error)) (interface{}, error) {
+	for {
+		if instance := p.get(); instance != nil {
+			return instance, nil
+		}
+
+		waits.Lock()
+		p.lock.Lock()
+		instance, owner, done := p.instance, p.creating, p.done
+		if instance == nil && owner == nil {
+			p.creating, p.done = state, make(chan struct{})
+		}
+		p.lock.Unlock()
+
+		switch {
+		case instance != nil:
+			waits.Unlock()
+			return instance, nil
+		case owner == nil:
+			waits.Unlock()
+			return p.create(create)
+		}
+
+		err := state.waitFor(p, owner)
+		waits.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		<-done
+		state.stopWaiting()
+	}
+}
+
+// create calls create for the resolution that claimed the slot, and wakes
+// up any waiting for it, even if create panics.
+func (p *instanceSlot) create(create func() (interface{}, error)) (instance interface{}, err error) {
+	defer func() {
+		waits.Lock()
+		p.lock.Lock()
+		if err == nil {
+			p.instance = instance
+		}
+		done := p.done
+		p.creating, p.done = nil, nil
+		p.lock.Unlock()
+		waits.Unlock()
+		close(done)
+	}()
+	return create()
+}
+
+// resolvingScope is the scope passed to a ScopeInitializer: resolving from
+// it is part of the resolve in progress while that is running.
+type resolvingScope struct {
+	*registrationContext
+	state *resolveState
+}
+
+func (p *resolvingScope) Resolve(target interface{}) (interface{}, error) {
+	return p.resolveCore(p.state.active(), instanceToType(target), "")
+}
+
+func (p *resolvingScope) ResolveNamed(target interface{}, name string) (interface{}, error) {
+	return p.resolveCore(p.state.active(), instanceToType(target), name)
+}
+
+func (p *resolvingScope) TryResolve(target interface{}) (interface{}, error) {
+	return p.tryResolve(p.state.active(), target)
+}
+
+func (p *resolvingScope) ResolveAll(target interface{}) ([]interface{}, error) {
+	if state := p.state.active(); state != nil {
+		return p.resolveAll(state, target)
+	}
+	return p.registrationContext.ResolveAll(target)
+}
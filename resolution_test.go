@@ -0,0 +1,226 @@
+package godi
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	ICycleA interface {
+		A()
+	}
+	ICycleB interface {
+		B()
+	}
+	TCycleA struct {
+		B ICycleB `godi:""`
+	}
+	TCycleB struct {
+		A ICycleA `godi:""`
+	}
+	TCycleInitializer struct{}
+	TCallbackA        struct{ b ICycleB }
+	TCallbackB        struct{ a ICycleA }
+)
+
+func (p *TCycleA) A()    {}
+func (p *TCycleB) B()    {}
+func (p *TCallbackA) A() {}
+func (p *TCallbackB) B() {}
+
+func (p TCycleInitializer) CanInitialize(instance interface{}, typeName string) bool {
+	return typeName == "godi.T1"
+}
+
+func (p TCycleInitializer) Initialize(instance interface{}, typeName string) (interface{}, error) {
+	return nil, errors.New("InitializeInScope should be called instead")
+}
+
+func (p TCycleInitializer) InitializeInScope(scope RegistrationContext, instance interface{}, typeName string) (interface{}, error) {
+	return scope.Resolve((*I1)(nil))
+}
+
+func (s *GoDiTestSuite) TestCycleFields() {
+	RegisterTypeImplementor((*ICycleA)(nil), TCycleA{}, false, nil)
+	RegisterTypeImplementor((*ICycleB)(nil), TCycleB{}, false, nil)
+
+	_, err := Resolve((*ICycleA)(nil))
+
+	var cycle *CycleError
+	assert.True(s.T(), errors.As(err, &cycle))
+	assert.Equal(s.T(), []string{"godi.ICycleA", "godi.ICycleB", "godi.ICycleA"}, cycle.Path)
+	assert.Equal(s.T(), "Dependency cycle detected: godi.ICycleA -> godi.ICycleB -> godi.ICycleA", cycle.Error())
+}
+
+func (s *GoDiTestSuite) TestCycleProviderScopes() {
+	// transient, so it is created against the child scope that has B.
+	RegisterProvider((*ICycleA)(nil), func(b ICycleB) *TCycleA {
+		return &TCycleA{B: b}
+	}, false)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterProvider((*ICycleB)(nil), func(a ICycleA) *TCycleB {
+		return &TCycleB{A: a}
+	}, true)
+
+	_, err := scope.Resolve((*ICycleB)(nil))
+
+	var cycle *CycleError
+	assert.True(s.T(), errors.As(err, &cycle))
+	assert.Equal(s.T(), []string{"godi.ICycleB", "godi.ICycleA", "godi.ICycleB"}, cycle.Path)
+
+	// a failed resolve doesn't leave anything behind
+	_, err = scope.Resolve((*ICycleB)(nil))
+	assert.True(s.T(), errors.As(err, &cycle))
+	assert.Equal(s.T(), 3, len(cycle.Path))
+}
+
+func (s *GoDiTestSuite) TestCycleInstanceInitializer() {
+	RegisterInstanceInitializer(TCycleInitializer{})
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+
+	_, err := Resolve((*I1)(nil))
+
+	var cycle *CycleError
+	assert.True(s.T(), errors.As(err, &cycle))
+	assert.Equal(s.T(), []string{"godi.I1", "godi.I1"}, cycle.Path)
+}
+
+func (s *GoDiTestSuite) TestNoCycleConcurrent() {
+	// resolving the same type on many goroutines is not a cycle
+	RegisterTypeImplementor((*I1)(nil), T3{}, false, nil)
+
+	wait := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, err := Resolve((*I1)(nil))
+			assert.Nil(s.T(), err)
+		}()
+	}
+	wait.Wait()
+}
+
+func (s *GoDiTestSuite) TestCycleAcrossGoroutines() {
+	// A and B are each created on their own goroutine, and each needs the
+	// other once both are in flight, which would deadlock.
+	aStarted, bStarted := make(chan struct{}), make(chan struct{})
+	var aOnce, bOnce sync.Once
+
+	RegisterProvider((*ICycleA)(nil), func(b Provider[ICycleB]) (*TCycleA, error) {
+		aOnce.Do(func() { close(aStarted) })
+		<-bStarted
+		inner, err := b.Get()
+		return &TCycleA{B: inner}, err
+	}, true)
+	RegisterProvider((*ICycleB)(nil), func(a Provider[ICycleA]) (*TCycleB, error) {
+		bOnce.Do(func() { close(bStarted) })
+		<-aStarted
+		inner, err := a.Get()
+		return &TCycleB{A: inner}, err
+	}, true)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := Resolve((*ICycleA)(nil))
+		errs <- err
+	}()
+	go func() {
+		_, err := Resolve((*ICycleB)(nil))
+		errs <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			var cycle *CycleError
+			assert.True(s.T(), errors.As(err, &cycle))
+		case <-time.After(5 * time.Second):
+			s.T().Fatal("Resolves deadlocked")
+		}
+	}
+}
+
+// registerCallbackCycle registers A and B with callbacks that each resolve
+// the other through resolve, rather than the scope godi is resolving from.
+func registerCallbackCycle(ctx RegistrationContext, cached bool, resolve func(interface{}) (interface{}, error)) {
+	ctx.RegisterTypeImplementor((*ICycleA)(nil), TCallbackA{}, cached, func(instance interface{}) (bool, error) {
+		b, err := resolve((*ICycleB)(nil))
+		if err == nil {
+			instance.(*TCallbackA).b = b.(ICycleB)
+		}
+		return false, err
+	})
+	ctx.RegisterTypeImplementor((*ICycleB)(nil), TCallbackB{}, cached, func(instance interface{}) (bool, error) {
+		a, err := resolve((*ICycleA)(nil))
+		if err == nil {
+			instance.(*TCallbackB).a = a.(ICycleA)
+		}
+		return false, err
+	})
+}
+
+// resolveCycle resolves ICycleA from ctx, and checks that it fails with the
+// cycle back to A rather than recursing forever or deadlocking.
+func (s *GoDiTestSuite) resolveCycle(ctx RegistrationContext) {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := ctx.Resolve((*ICycleA)(nil))
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		var cycle *CycleError
+		assert.True(s.T(), errors.As(err, &cycle))
+		if cycle != nil {
+			assert.Equal(s.T(), []string{"godi.ICycleA", "godi.ICycleB", "godi.ICycleA"}, cycle.Path)
+		}
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("Resolve deadlocked")
+	}
+}
+
+func (s *GoDiTestSuite) TestCycleCallbackPackageResolve() {
+	registerCallbackCycle(rootContext, false, Resolve)
+	s.resolveCycle(rootContext)
+}
+
+func (s *GoDiTestSuite) TestCycleCallbackPackageResolveCached() {
+	registerCallbackCycle(rootContext, true, Resolve)
+	s.resolveCycle(rootContext)
+
+	// nothing was cached, so resolving again reports the cycle again.
+	s.resolveCycle(rootContext)
+}
+
+func (s *GoDiTestSuite) TestCycleCallbackCapturedScope() {
+	for _, cached := range []bool{false, true} {
+		scope := CreateScope(false)
+		registerCallbackCycle(scope, cached, scope.Resolve)
+		s.resolveCycle(scope)
+		scope.Close()
+	}
+}
+
+func (s *GoDiTestSuite) TestCallbackPackageResolve() {
+	// resolving other types from a callback isn't a cycle, and the chain
+	// doesn't outlive the resolve.
+	RegisterTypeImplementor((*I1)(nil), T2{}, true, nil)
+	RegisterTypeImplementor((*ICycleA)(nil), TCallbackA{}, true, func(instance interface{}) (bool, error) {
+		_, err := Resolve((*I1)(nil))
+		return false, err
+	})
+
+	_, err := Resolve((*ICycleA)(nil))
+	assert.Nil(s.T(), err)
+	_, err = Resolve((*I1)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), int64(0), atomic.LoadInt64(&creatingCount))
+}
@@ -0,0 +1,98 @@
+package godi
+
+import (
+	"context"
+	"sync"
+)
+
+// --------
+//
+// Pushed scopes.  CreateScope(true) pushes the new scope onto a
+// process-wide stack, making it current for the package-level functions on
+// every goroutine until it is closed.  Closing a pushed scope removes that
+// scope wherever it is in the stack, so closing scopes out of order can't
+// pop another scope from under whoever pushed it.
+//
+// Since that stack is shared, goroutines that need scopes of their own, such
+// as parallel tests or request handlers, push them onto a context.Context
+// with PushScope instead, and use the ...Context variants of the
+// package-level functions, which use the scope the context carries.
+//
+// --------
+
+var pushedScopes = struct {
+	sync.RWMutex
+	stack []*registrationContext
+}{}
+
+// currentContext returns the innermost pushed scope, or the root scope.
+func currentContext() *registrationContext {
+	pushedScopes.RLock()
+	defer pushedScopes.RUnlock()
+
+	if n := len(pushedScopes.stack); n > 0 {
+		return pushedScopes.stack[n-1]
+	}
+	return rootContext
+}
+
+func pushCurrent(scope *registrationContext) {
+	pushedScopes.Lock()
+	defer pushedScopes.Unlock()
+
+	pushedScopes.stack = append(pushedScopes.stack, scope)
+}
+
+// popCurrent removes scope from the stack, wherever it is.
+func popCurrent(scope *registrationContext) {
+	pushedScopes.Lock()
+	defer pushedScopes.Unlock()
+
+	stack := pushedScopes.stack
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == scope {
+			pushedScopes.stack = append(stack[:i:i], stack[i+1:]...)
+			return
+		}
+	}
+}
+
+func resetPushedScopes() {
+	pushedScopes.Lock()
+	defer pushedScopes.Unlock()
+
+	pushedScopes.stack = nil
+}
+
+// PushScope creates a scope below the one ctx carries, or below the current
+// scope if it doesn't carry one, and returns a copy of ctx carrying it.
+// Only code given the returned context sees the new scope, so each
+// goroutine or request can push its own.  Close the scope when done with it.
+func PushScope(ctx context.Context) (context.Context, RegistrationContext) {
+	scope := contextOrCurrent(ScopeFrom(ctx)).CreateScope()
+	return WithScope(ctx, scope), scope
+}
+
+// RegisterInstanceImplementorContext is RegisterInstanceImplementor for the
+// scope ctx carries, or the current scope if it doesn't carry one.
+func RegisterInstanceImplementorContext(ctx context.Context, target interface{}, instance interface{}, opts ...RegistrationOption) (Closable, error) {
+	return contextOrCurrent(ScopeFrom(ctx)).RegisterInstanceImplementor(target, instance, opts...)
+}
+
+// RegisterTypeImplementorContext is RegisterTypeImplementor for the scope
+// ctx carries, or the current scope if it doesn't carry one.
+func RegisterTypeImplementorContext(ctx context.Context, target interface{}, implementorType interface{}, cached bool, init InitializeCallback, opts ...RegistrationOption) (Closable, error) {
+	return contextOrCurrent(ScopeFrom(ctx)).RegisterTypeImplementor(target, implementorType, cached, init, opts...)
+}
+
+// RegisterProviderContext is RegisterProvider for the scope ctx carries, or
+// the current scope if it doesn't carry one.
+func RegisterProviderContext(ctx context.Context, target interface{}, provider interface{}, cached bool, opts ...RegistrationOption) (Closable, error) {
+	return contextOrCurrent(ScopeFrom(ctx)).RegisterProvider(target, provider, cached, opts...)
+}
+
+// ResolveNamedContext is ResolveNamed for the scope ctx carries, or the
+// current scope if it doesn't carry one.  See also ResolveContext.
+func ResolveNamedContext(ctx context.Context, target interface{}, name string) (interface{}, error) {
+	return contextOrCurrent(ScopeFrom(ctx)).ResolveNamed(target, name)
+}
@@ -0,0 +1,148 @@
+package godi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestScopePerGoroutine() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+
+	n := 10
+	wait := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+
+			name := fmt.Sprintf("scope %d", i)
+			scope := CreateScope(false)
+			scope.RegisterInstanceImplementor((*I1)(nil), T1{s: name})
+			ctx := WithScope(context.Background(), scope)
+
+			r, err := ResolveContext(ctx, (*I1)(nil))
+			assert.Nil(s.T(), err)
+			assert.Equal(s.T(), name, r.(I1).F1())
+
+			scope.Close()
+		}(i)
+	}
+	wait.Wait()
+
+	r, _ := Resolve((*I1)(nil))
+	assert.Equal(s.T(), "root", r.(I1).F1())
+}
+
+func (s *GoDiTestSuite) TestPushScopeOtherGoroutines() {
+	scope := CreateScope(true)
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "pushed"})
+
+	// goroutines started after the push see it
+	found := make(chan string)
+	go func() {
+		r, _ := Resolve((*I1)(nil))
+		found <- r.(I1).F1()
+	}()
+	assert.Equal(s.T(), "pushed", <-found)
+
+	scope.Close()
+	_, err := Resolve((*I1)(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestPushScopeNested() {
+	outer := CreateScope(true)
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "outer"})
+
+	inner := CreateScope(true)
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "inner"})
+
+	r, _ := Resolve((*I1)(nil))
+	assert.Equal(s.T(), "inner", r.(I1).F1())
+
+	// closing out of order only removes that scope
+	outer.Close()
+	r, _ = Resolve((*I1)(nil))
+	assert.Equal(s.T(), "inner", r.(I1).F1())
+
+	inner.Close()
+	_, err := Resolve((*I1)(nil))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestConcurrentRegistration() {
+	n := 20
+	wait := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			RegisterType(T2{})
+			RegisterByName("godi.I1", "godi.T2", false)
+
+			token, err := RegisterTypeImplementor((*I1)(nil), T3{}, true, nil)
+			assert.Nil(s.T(), err)
+			RegisterInstanceInitializer(TestInitializer{})
+
+			scope := CreateScope(false)
+			scope.RegisterTypeImplementor((*I1)(nil), T1{}, false, nil)
+			_, err = scope.Resolve((*I1)(nil))
+			assert.Nil(s.T(), err)
+			_, err = ResolveAll((*I1)(nil))
+			assert.Nil(s.T(), err)
+			scope.Close()
+
+			token.Close()
+			token.Close()
+		}()
+	}
+	wait.Wait()
+
+	assert.Equal(s.T(), 1, len(*getRegisteredTypes()))
+}
+
+func (s *GoDiTestSuite) TestPushScopeContext() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+
+	n := 10
+	wait := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+
+			name := fmt.Sprintf("scope %d", i)
+			ctx, scope := PushScope(context.Background())
+			defer scope.Close()
+			_, err := RegisterInstanceImplementorContext(ctx, (*I1)(nil), T1{s: name})
+			assert.Nil(s.T(), err)
+
+			r, err := ResolveContext(ctx, (*I1)(nil))
+			assert.Nil(s.T(), err)
+			assert.Equal(s.T(), name, r.(I1).F1())
+
+			// pushing from the context nests below its scope
+			inner, innerScope := PushScope(ctx)
+			defer innerScope.Close()
+			RegisterTypeImplementorContext(inner, (*I1)(nil), T2{}, false, nil, Named("t2"))
+
+			r, err = ResolveNamedContext(inner, (*I1)(nil), "t2")
+			assert.Nil(s.T(), err)
+			assert.Equal(s.T(), "t2", r.(I1).F1())
+			r, _ = ResolveContext(inner, (*I1)(nil))
+			assert.Equal(s.T(), name, r.(I1).F1())
+
+			_, err = ResolveNamedContext(ctx, (*I1)(nil), "t2")
+			assert.NotNil(s.T(), err)
+		}(i)
+	}
+	wait.Wait()
+
+	// the package-level scope is unaffected
+	r, _ := Resolve((*I1)(nil))
+	assert.Equal(s.T(), "root", r.(I1).F1())
+}
@@ -0,0 +1,246 @@
+package godi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --------
+//
+// Resolution tracing records how a Resolve was satisfied: the scopes
+// searched, the registration chosen and the ones it shadowed, whether a
+// cached instance was reused, each creation and initialization step with
+// its timing and outcome, and any resolutions nested inside those steps.
+//
+// Traces are collected on the resolve state (see resolution.go), and only
+// while ResolveWithTrace is running or a TraceHandler is set, so untraced
+// resolves don't pay for them.
+//
+// --------
+
+// ResolveTrace describes one resolution and the resolutions nested in it.
+type ResolveTrace struct {
+	Target string
+	Name   string
+
+	// ScopesSearched lists the scopes looked in, innermost first.  It is
+	// empty for resolutions made by ResolveAll.
+	ScopesSearched []string
+
+	// Scope, Implementor and Lifetime describe the registration chosen.
+	Scope       string
+	Implementor string
+	Lifetime    string
+
+	// Shadowed lists the other registrations for the same target and name,
+	// as "scope: implementor", that weren't chosen.
+	Shadowed []string
+
+	// Inactive lists the registrations for the same target and name that
+	// were skipped because their profiles or conditions don't hold, as
+	// "scope: implementor (condition)", and Profiles the profiles that were
+	// active.
+	Inactive []string
+	Profiles []string
+
+	// CacheHit is true if an existing instance was returned.  The value
+	// given to RegisterInstanceImplementor is decorated the first time it
+	// is resolved, so that resolve isn't a cache hit.
+	CacheHit bool
+
+	Steps       []*TraceStep
+	Resolutions []*ResolveTrace
+	Duration    time.Duration
+	Err         error
+}
+
+// TraceStep is a single creation or initialization step.
+type TraceStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// TraceHandler receives the trace of every top-level resolution while it is
+// set with SetTraceHandler.
+type TraceHandler func(*ResolveTrace)
+
+var traceHandler = struct {
+	sync.RWMutex
+	handler TraceHandler
+}{}
+
+// tracers counts the reasons to trace: a handler being set, and each
+// ResolveWithTrace in progress.
+var tracers int64
+
+// SetTraceHandler turns tracing of every resolution on, passing each
+// top-level trace to handler, or off if handler is nil.  This is intended
+// for debugging, since tracing slows resolution down.
+func SetTraceHandler(handler TraceHandler) {
+	traceHandler.Lock()
+	defer traceHandler.Unlock()
+
+	switch {
+	case traceHandler.handler == nil && handler != nil:
+		atomic.AddInt64(&tracers, 1)
+	case traceHandler.handler != nil && handler == nil:
+		atomic.AddInt64(&tracers, -1)
+	}
+	traceHandler.handler = handler
+}
+
+// WriteTraces returns a TraceHandler that writes each trace to w.
+func WriteTraces(w io.Writer) TraceHandler {
+	var lock sync.Mutex
+	return func(t *ResolveTrace) {
+		lock.Lock()
+		defer lock.Unlock()
+		io.WriteString(w, t.String())
+	}
+}
+
+func tracing() bool {
+	return atomic.LoadInt64(&tracers) > 0
+}
+
+// ResolveWithTrace resolves target like Resolve, and also returns a trace of
+// how it was resolved.
+func (p *registrationContext) ResolveWithTrace(target interface{}) (interface{}, *ResolveTrace, error) {
+	atomic.AddInt64(&tracers, 1)
+	defer atomic.AddInt64(&tracers, -1)
+
+	// the trace is captured as a top-level trace, even if the resolve
+	// continues one in progress.
+	state := newResolveState()
+	defer state.leave()
+	state.trace, state.capturing = nil, true
+
+	instance, err := p.resolveCore(state, instanceToType(target), "")
+	return instance, state.captured, err
+}
+
+// beginTrace opens a trace node for a resolution of typeName made from
+// state, nested in state's trace.  Returns nil if not tracing.
+func beginTrace(state *resolveState, typeName string, name string) *ResolveTrace {
+	if !tracing() {
+		return nil
+	}
+
+	trace := &ResolveTrace{Target: typeName, Name: name}
+	if state.trace != nil {
+		state.trace.Resolutions = append(state.trace.Resolutions, trace)
+	}
+	return trace
+}
+
+// endTrace closes a trace opened from state.  Top-level traces are handed
+// to ResolveWithTrace or the TraceHandler.
+func endTrace(state *resolveState, trace *ResolveTrace, start time.Time, err error) {
+	if trace == nil {
+		return
+	}
+	trace.Duration = time.Since(start)
+	trace.Err = err
+
+	topLevel := state.trace == nil
+	if topLevel && state.capturing {
+		state.captured = trace
+	}
+
+	if topLevel {
+		traceHandler.RLock()
+		handler := traceHandler.handler
+		traceHandler.RUnlock()
+		if handler != nil {
+			handler(trace)
+		}
+	}
+}
+
+// traceRegistration records the registration chosen for trace.
+func traceRegistration(trace *ResolveTrace, reg *typeRegistration) {
+	if trace == nil {
+		return
+	}
+	trace.Scope = reg.scope.name
+	trace.Implementor = reg.implType.typeName
+	trace.Lifetime = reg.lifetime()
+}
+
+// traceShadowed records the other registrations for reg's target and name
+// visible from scope, which reg was chosen over.
+func traceShadowed(trace *ResolveTrace, scope *registrationContext, reg *typeRegistration) {
+	if trace == nil {
+		return
+	}
+	for _, ctx := range scope.lookupChain() {
+		regs, _ := ctx.findAllRegistrations(reg.targetType.typeName)
+		for i := len(regs) - 1; i >= 0; i-- {
+			if other := regs[i]; other != reg && other.name == reg.name {
+				trace.Shadowed = append(trace.Shadowed, ctx.name+": "+other.implType.typeName)
+			}
+		}
+	}
+}
+
+// traceStep records a step of state's trace.
+func traceStep(state *resolveState, name string, start time.Time, err error) {
+	if state.trace != nil {
+		state.trace.Steps = append(state.trace.Steps, &TraceStep{Name: name, Duration: time.Since(start), Err: err})
+	}
+}
+
+// String formats the trace as an indented tree.
+func (p *ResolveTrace) String() string {
+	var b strings.Builder
+	p.format(&b, "")
+	return b.String()
+}
+
+func (p *ResolveTrace) format(b *strings.Builder, indent string) {
+	target := p.Target
+	if p.Name != "" {
+		target += "[" + p.Name + "]"
+	}
+	fmt.Fprintf(b, "%sresolve %s (%v)", indent, target, p.Duration)
+	if p.Err != nil {
+		fmt.Fprintf(b, " error: %v", p.Err)
+	}
+	b.WriteString("\n")
+
+	indent += "  "
+	if len(p.ScopesSearched) > 0 {
+		fmt.Fprintf(b, "%ssearched: %s\n", indent, strings.Join(p.ScopesSearched, ", "))
+	}
+	if p.Implementor != "" {
+		cache := "miss"
+		if p.CacheHit {
+			cache = "hit"
+		}
+		fmt.Fprintf(b, "%schose: %s: %s (%s, cache %s)\n", indent, p.Scope, p.Implementor, p.Lifetime, cache)
+	}
+	for _, s := range p.Shadowed {
+		fmt.Fprintf(b, "%sshadowed: %s\n", indent, s)
+	}
+	for _, s := range p.Inactive {
+		fmt.Fprintf(b, "%sinactive: %s\n", indent, s)
+	}
+	if len(p.Inactive) > 0 {
+		fmt.Fprintf(b, "%sprofiles: %s\n", indent, strings.Join(p.Profiles, ", "))
+	}
+	for _, s := range p.Steps {
+		fmt.Fprintf(b, "%sstep %s (%v)", indent, s.Name, s.Duration)
+		if s.Err != nil {
+			fmt.Fprintf(b, " error: %v", s.Err)
+		}
+		b.WriteString("\n")
+	}
+	for _, r := range p.Resolutions {
+		r.format(b, indent)
+	}
+}
@@ -0,0 +1,87 @@
+package godi
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestResolveWithTrace() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "root"})
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.RegisterInstanceImplementor((*I1)(nil), T1{s: "scope"})
+	scope.RegisterProvider((*I3)(nil), func(dep I1) *TProvided {
+		return &TProvided{dep: dep}
+	}, true)
+
+	r, trace, err := scope.ResolveWithTrace((*I3)(nil))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "scope", r.(I3).F3())
+
+	assert.Equal(s.T(), "godi.I3", trace.Target)
+	assert.Equal(s.T(), []string{scope.(*registrationContext).name}, trace.ScopesSearched)
+	assert.Equal(s.T(), scope.(*registrationContext).name, trace.Scope)
+	assert.Equal(s.T(), LifetimeCached, trace.Lifetime)
+	assert.False(s.T(), trace.CacheHit)
+
+	assert.Equal(s.T(), 2, len(trace.Steps))
+	assert.Equal(s.T(), "provider", trace.Steps[0].Name)
+	assert.Equal(s.T(), "inject fields", trace.Steps[1].Name)
+
+	// the provider's parameter is a nested resolution, which shadows root's
+	assert.Equal(s.T(), 1, len(trace.Resolutions))
+	nested := trace.Resolutions[0]
+	assert.Equal(s.T(), "godi.I1", nested.Target)
+	assert.Equal(s.T(), scope.(*registrationContext).name, nested.Scope)
+	assert.Equal(s.T(), []string{"root: godi.T1"}, nested.Shadowed)
+	assert.Equal(s.T(), LifetimeInstance, nested.Lifetime)
+	// instance registrations are decorated, and cached, on their first resolve
+	assert.False(s.T(), nested.CacheHit)
+
+	assert.True(s.T(), strings.Contains(trace.String(), "  resolve godi.I1"))
+
+	_, trace, _ = scope.ResolveWithTrace((*I3)(nil))
+	assert.True(s.T(), trace.CacheHit)
+	assert.Equal(s.T(), 0, len(trace.Steps))
+}
+
+func (s *GoDiTestSuite) TestResolveWithTraceInitializers() {
+	RegisterTypeImplementor((*I1)(nil), T1{}, false, func(instance interface{}) (bool, error) {
+		return true, nil
+	})
+	RegisterInstanceInitializer(TestInitializer{})
+
+	_, trace, err := ResolveWithTrace((*I1)(nil))
+	assert.Nil(s.T(), err)
+
+	var names []string
+	for _, step := range trace.Steps {
+		names = append(names, step.Name)
+	}
+	assert.Equal(s.T(), []string{"construct", "inject fields", "InitializeCallback", "InstanceInitializer godi.TestInitializer"}, names)
+	assert.Equal(s.T(), LifetimeTransient, trace.Lifetime)
+}
+
+func (s *GoDiTestSuite) TestResolveWithTraceNotFound() {
+	_, trace, err := ResolveWithTrace((*I1)(nil))
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	assert.Equal(s.T(), err, trace.Err)
+	assert.Equal(s.T(), []string{"root"}, trace.ScopesSearched)
+	assert.Equal(s.T(), "", trace.Implementor)
+}
+
+func (s *GoDiTestSuite) TestTraceHandler() {
+	RegisterInstanceImplementor((*I1)(nil), T1{})
+
+	var traces []*ResolveTrace
+	SetTraceHandler(func(t *ResolveTrace) { traces = append(traces, t) })
+	Resolve((*I1)(nil))
+	SetTraceHandler(nil)
+	Resolve((*I1)(nil))
+
+	assert.Equal(s.T(), 1, len(traces))
+	assert.Equal(s.T(), "godi.I1", traces[0].Target)
+}
@@ -1,7 +1,6 @@
 package godi
 
 import (
-	"fmt"
 	"reflect"
 	"strings"
 )
@@ -34,15 +33,20 @@ func newtypeInfo(typeName string, reflectType *reflect.Type) *typeInfo {
 	return ti
 }
 
-func (p *typeInfo) Type() reflect.Type {
-	if p.reflectType == nil {
-		t := typeMap[p.typeName]
-		if t == nil {
-			panic(fmt.Sprintf("Can't find type '%s', did you forget to register it?", p.typeName))
-		}
-		p.reflectType = t
+// Type returns the reflect.Type, looking it up by name if needed.  Returns an
+// *UnknownTypeError if the name hasn't been registered with RegisterType.
+func (p *typeInfo) Type() (reflect.Type, error) {
+	if p.reflectType != nil {
+		return *p.reflectType, nil
 	}
-	return *p.reflectType
+
+	// name-based types are looked up each time, since they may be
+	// registered after the registration is made.
+	t := lookupType(p.typeName)
+	if t == nil {
+		return nil, &UnknownTypeError{TypeName: p.typeName}
+	}
+	return *t, nil
 }
 
 //
@@ -1,72 +1,152 @@
 package godi
 
 import (
-	"fmt"
 	"reflect"
-	"sync"
 )
 
 type InitializeCallback func(interface{}) (bool, error)
 
 type typeRegistration struct {
-	targetType  *typeInfo
-	implType    *typeInfo
-	initializer InitializeCallback
-	instance    interface{}
-	cached      bool
-	id          int
-	lock        sync.RWMutex
+	targetType    *typeInfo
+	implType      *typeInfo
+	scope         *registrationContext
+	name          string
+	initializer   InitializeCallback
+	provider      reflect.Value
+	value         interface{} // given to RegisterInstanceImplementor
+	slot          instanceSlot
+	isInstance    bool
+	cached        bool
+	scoped        bool
+	shadowParents bool
+	isDefault     bool
+	profiles      []string
+	conditions    []func() bool
+	id            int
+}
+
+// String returns the target name, qualified by the registration name if set.
+func (p *typeRegistration) String() string {
+	if p.name != "" {
+		return p.targetType.typeName + "[" + p.name + "]"
+	}
+	return p.targetType.typeName
 }
 
 func (p *typeRegistration) ensureImplementor(impl reflect.Type, target reflect.Type) error {
+	notImplemented := &NotImplementedError{Implementor: typeToString(impl), Target: typeToString(target)}
+
+	if target.Kind() != reflect.Interface {
+		// concrete targets can only be implemented by themselves
+		if impl != target {
+			return notImplemented
+		}
+		return nil
+	}
 	if !impl.Implements(target) {
 		// since a method can be declared on the pointer, you need to check both
 		if !reflect.PtrTo(impl).Implements(target) {
-			return fmt.Errorf("Expected %v to implement %v", impl, target)
+			return notImplemented
 		}
 	}
 	return nil
 }
 
-func (p *typeRegistration) realize() (interface{}, bool, error) {
+// home returns the scope a cached instance of the registration belongs to:
+// it is created, injected, initialized and decorated against that scope,
+// whichever scope it is resolved from, and its lifecycle is managed there.
+// Overrides stand in for registrations in the root scope, so a cached
+// override belongs there rather than to the override scope.
+func (p *typeRegistration) home() *registrationContext {
+	if p.scope == overrides.context {
+		return rootContext
+	}
+	return p.scope
+}
 
-	// do we have an instance?
-	//
+// realize returns the registration's instance, calling create to build one
+// on state if needed.  For cached registrations, create is called at most
+// once successfully and the result is kept for subsequent calls.
+func (p *typeRegistration) realize(state *resolveState, create func() (interface{}, error)) (interface{}, error) {
+	if !p.cached {
+		return create()
+	}
+	return p.slot.realize(state, create)
+}
 
-	created := false
+// dependency is something a registration resolves when it creates an
+// instance: a provider parameter or an injected field.  Lazy and Provider
+// dependencies are deferred, and refer to their T, as do Optional ones.
+type dependency struct {
+	reflectType reflect.Type
+	name        string
+	optional    bool
+	deferred    bool
+}
 
-	create := func() interface{} {
-		created = true
-		return reflect.New(p.implType.Type()).Interface()
+func newDependency(t reflect.Type, name string, optional bool) dependency {
+	if target, ok := optionalTarget(t); ok {
+		t, optional = target, true
 	}
+	if target, ok := deferredTarget(t); ok {
+		return dependency{reflectType: target, name: name, optional: optional, deferred: true}
+	}
+	return dependency{reflectType: t, name: name, optional: optional}
+}
 
-	// only lock if we're a cached item
-	// we lock here to make sure we don't create the item twice.
-	//
-	p.lock.RLock()
-
-	var instance interface{} = p.instance
-	needsCachedInstance := p.cached && p.instance == nil
-
-	if needsCachedInstance {
-		// if we need an instance, upgrade the lock
-		p.lock.RUnlock()
-		p.lock.Lock()
-
-		// check again to avoid races
-		if p.instance == nil {
-			instance = create()
-			p.instance = instance
-		} else {
-			instance = p.instance
+func (p dependency) typeName() string {
+	return typeToString(p.reflectType)
+}
+
+// dependencies returns what creating an instance for this registration will
+// resolve.  Instance registrations, and registrations whose implementor type
+// isn't known, have none.
+func (p *typeRegistration) dependencies() ([]dependency, error) {
+	if p.isInstance {
+		return nil, nil
+	}
+
+	var deps []dependency
+	if p.provider.IsValid() {
+		ft := p.provider.Type()
+		for i := 0; i < ft.NumIn(); i++ {
+			deps = append(deps, newDependency(ft.In(i), "", false))
 		}
-		defer p.lock.Unlock()
-	} else {
-		defer p.lock.RUnlock()
 	}
 
-	if !p.cached {
-		instance = create()
+	implType, err := p.implType.Type()
+	if err != nil || implType.Kind() != reflect.Struct {
+		return deps, nil
+	}
+
+	plan := planFields(implType)
+	if plan.err != nil {
+		return deps, plan.err
+	}
+	for _, fi := range plan.fields {
+		// unexported fields aren't injected into provider results.
+		if fi.value != "" || !fi.exported && p.provider.IsValid() {
+			continue
+		}
+		deps = append(deps, newDependency(fi.fieldType, fi.name, fi.optional))
+	}
+	return deps, nil
+}
+
+// construct creates a new, uninitialized instance of the implementor, either
+// by calling the provider or creating a zero value.
+func (p *typeRegistration) construct(state *resolveState, ctx *registrationContext) (interface{}, error) {
+	if p.provider.IsValid() {
+		instance, err := ctx.callProvider(state, p.provider)
+		if err != nil {
+			return nil, newInitError(p, err)
+		}
+		return instance, nil
+	}
+
+	t, err := p.implType.Type()
+	if err != nil {
+		return nil, err
 	}
-	return instance, created, nil
+	return reflect.New(t).Interface(), nil
 }
@@ -0,0 +1,477 @@
+package godi
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------
+//
+// Value injection sets scalar configuration (DSNs, timeouts, feature flags)
+// on fields tagged `godi:"value=<key>"`, from the ValueSources added to the
+// resolving scope and its parents.  Keys are dotted paths, e.g. db.timeout.
+//
+// Sources are searched innermost scope first, and within a scope the most
+// recently added source first, so later and more specific sources take
+// precedence.  Sources(...) composes several sources into one the same way.
+//
+// Values are converted to the field's type: strings, bools, numbers,
+// time.Duration, slices (from lists or comma-separated strings), pointers to
+// any of these, and nested structs, whose fields are looked up as
+// <key>.<field>.
+//
+// --------
+
+// ValueSource looks up configuration values by dotted key.
+type ValueSource interface {
+	Lookup(key string) (interface{}, bool)
+}
+
+// ValueSourceFunc adapts a function to a ValueSource.
+type ValueSourceFunc func(key string) (interface{}, bool)
+
+// Lookup calls the function.
+func (p ValueSourceFunc) Lookup(key string) (interface{}, bool) {
+	return p(key)
+}
+
+// Sources composes sources into one, where earlier sources take precedence
+// over later ones.
+func Sources(sources ...ValueSource) ValueSource {
+	return ValueSourceFunc(func(key string) (interface{}, bool) {
+		for _, source := range sources {
+			if value, ok := source.Lookup(key); ok {
+				return value, true
+			}
+		}
+		return nil, false
+	})
+}
+
+// EnvSource looks up keys in the environment as prefix + the key upper
+// cased, with dots and dashes replaced by underscores, so db.timeout with
+// prefix APP_ is APP_DB_TIMEOUT.
+func EnvSource(prefix string) ValueSource {
+	replacer := strings.NewReplacer(".", "_", "-", "_")
+	return ValueSourceFunc(func(key string) (interface{}, bool) {
+		return os.LookupEnv(prefix + strings.ToUpper(replacer.Replace(key)))
+	})
+}
+
+// MapSource looks up keys in m, first as given and then as a path through
+// nested maps, e.g. db.timeout as m["db"]["timeout"].
+func MapSource(m map[string]interface{}) ValueSource {
+	return ValueSourceFunc(func(key string) (interface{}, bool) {
+		return lookupPath(m, key)
+	})
+}
+
+// JSONSource reads a JSON object and looks up keys as paths through it, see
+// MapSource.
+func JSONSource(r io.Reader) (ValueSource, error) {
+	var m map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("Reading JSON values: %w", err)
+	}
+	return MapSource(m), nil
+}
+
+// JSONFileSource reads a JSON object from a file, see JSONSource.
+func JSONFileSource(path string) (ValueSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	source, err := JSONSource(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return source, nil
+}
+
+// FlagSource looks up keys as the names of flags in fs.  Only flags that were
+// set on the command line are found, so their defaults don't take
+// precedence over other sources.
+func FlagSource(fs *flag.FlagSet) ValueSource {
+	return ValueSourceFunc(func(key string) (interface{}, bool) {
+		var value interface{}
+		found := false
+		fs.Visit(func(f *flag.Flag) {
+			if !found && strings.EqualFold(f.Name, key) {
+				value, found = flagValue(f), true
+			}
+		})
+		return value, found
+	})
+}
+
+// flagValue returns the typed value of f if it has one.
+func flagValue(f *flag.Flag) interface{} {
+	if getter, ok := f.Value.(flag.Getter); ok {
+		return getter.Get()
+	}
+	return f.Value.String()
+}
+
+// lookupPath finds key in m, first as given and then as a dotted path through
+// nested maps.  Path segments match keys case-insensitively if there is no
+// exact match.
+func lookupPath(m map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+
+	head, rest, nested := strings.Cut(key, ".")
+	value, ok := m[head]
+	if !ok {
+		for k, v := range m {
+			if strings.EqualFold(k, head) {
+				value, ok = v, true
+				break
+			}
+		}
+	}
+	if !ok || !nested {
+		return value, ok
+	}
+
+	child, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, false
+	}
+	return lookupPath(child, rest)
+}
+
+type valueSource struct {
+	source ValueSource
+}
+
+// AddValueSource adds a source of values for fields resolved from this scope
+// and its children.  It takes precedence over sources added before it, and
+// over those of parent scopes.  Closing the result removes it.
+func (p *registrationContext) AddValueSource(source ValueSource) Closable {
+	vs := &valueSource{source: source}
+
+	p.rwlock.Lock()
+	defer p.rwlock.Unlock()
+	p.values = append(p.values, vs)
+	return &valueSourceToken{context: p, source: vs}
+}
+
+type valueSourceToken struct {
+	context *registrationContext
+	source  *valueSource
+}
+
+func (p *valueSourceToken) Close() {
+	p.context.rwlock.Lock()
+	defer p.context.rwlock.Unlock()
+
+	for i, vs := range p.context.values {
+		if vs == p.source {
+			p.context.values = append(p.context.values[:i:i], p.context.values[i+1:]...)
+			return
+		}
+	}
+}
+
+// getValueSources returns a snapshot of this scope's sources.
+func (p *registrationContext) getValueSources() []*valueSource {
+	p.rwlock.RLock()
+	defer p.rwlock.RUnlock()
+	return append([]*valueSource(nil), p.values...)
+}
+
+// lookupValue finds key in the sources visible from this scope.
+func (p *registrationContext) lookupValue(key string) (interface{}, bool) {
+	for ctx := p; ctx != nil; ctx = ctx.getParent() {
+		sources := ctx.getValueSources()
+		for i := len(sources) - 1; i >= 0; i-- {
+			if value, ok := sources[i].source.Lookup(key); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// planHasValues returns true if instance is a pointer to a struct with value
+// fields.
+func planHasValues(instance interface{}) bool {
+	t := reflect.TypeOf(instance)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	return planFields(t.Elem()).hasValues
+}
+
+// injectValues sets the value fields of instance that are still zero.
+// constructed is as for injectFields.
+func (p *registrationContext) injectValues(instance interface{}, constructed bool) error {
+	v := reflect.ValueOf(instance)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+
+	plan := planFields(v.Type())
+	if plan.err != nil {
+		return plan.err
+	}
+
+	for _, fi := range plan.fields {
+		field, ok := injectableField(v, fi, constructed)
+		if !ok || fi.value == "" || !field.IsZero() {
+			continue
+		}
+
+		val, found, err := p.valueFor(fi.value, fi.fieldType)
+		if err == nil && !found {
+			switch {
+			case fi.hasDefault:
+				val, err = convertValue(fi.defaultValue, fi.fieldType)
+			case fi.optional:
+				continue
+			case val.IsValid():
+				// a struct of defaults
+			default:
+				err = fmt.Errorf("No value for '%s'", fi.value)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("Injecting value '%s' into %v.%s: %w", fi.value, v.Type(), fi.fieldName, err)
+		}
+		field.Set(val)
+	}
+	return nil
+}
+
+// valueFor looks up key and converts it to t.  Structs that aren't set as a
+// whole are filled field by field; if none of their fields are set but some
+// have defaults, the struct of defaults is returned but not found.
+func (p *registrationContext) valueFor(key string, t reflect.Type) (reflect.Value, bool, error) {
+	raw, found := p.lookupValue(key)
+	if found {
+		if _, isMap := raw.(map[string]interface{}); !isMap || !isStruct(t) {
+			v, err := convertValue(raw, t)
+			return v, true, err
+		}
+	}
+	if !isStruct(t) {
+		return reflect.Value{}, false, nil
+	}
+
+	elem := t
+	if t.Kind() == reflect.Ptr {
+		elem = t.Elem()
+	}
+	v := reflect.New(elem).Elem()
+	found, defaulted := false, false
+	for i := 0; i < elem.NumField(); i++ {
+		f := elem.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fi := fieldInjection{value: f.Name}
+		if tag, ok := f.Tag.Lookup(injectTag); ok {
+			if err := fi.parseTag(tag); err != nil {
+				return reflect.Value{}, false, fmt.Errorf("Invalid godi tag on %v.%s: %w", elem, f.Name, err)
+			}
+		}
+
+		sub, ok, err := p.valueFor(key+"."+fi.value, f.Type)
+		switch {
+		case err != nil:
+			return reflect.Value{}, false, err
+		case ok:
+			found = true
+		case fi.hasDefault:
+			if sub, err = convertValue(fi.defaultValue, f.Type); err != nil {
+				return reflect.Value{}, false, fmt.Errorf("%v.%s: %w", elem, f.Name, err)
+			}
+			defaulted = true
+		case sub.IsValid():
+			defaulted = true
+		default:
+			continue
+		}
+		v.Field(i).Set(sub)
+	}
+
+	if !found && !defaulted {
+		return reflect.Value{}, false, nil
+	}
+	if t.Kind() == reflect.Ptr {
+		v = v.Addr()
+	}
+	return v, found, nil
+}
+
+// isStruct returns true for structs, and pointers to them, that are filled
+// field by field.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// convertValue converts a value from a source to t.
+func convertValue(raw interface{}, t reflect.Type) (reflect.Value, error) {
+	if raw == nil {
+		return reflect.Value{}, fmt.Errorf("Can't convert nil to %v", t)
+	}
+	v := reflect.ValueOf(raw)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if v.Type().ConvertibleTo(t) && v.Kind() == t.Kind() {
+		return v.Convert(t), nil
+	}
+
+	s, isString := raw.(string)
+	fail := func(err error) (reflect.Value, error) {
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Can't convert %#v to %v: %w", raw, t, err)
+		}
+		return reflect.Value{}, fmt.Errorf("Can't convert %#v to %v", raw, t)
+	}
+
+	out := reflect.New(t).Elem()
+	switch {
+	case t == durationType && isString:
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return fail(err)
+		}
+		out.SetInt(int64(d))
+
+	case t.Kind() == reflect.Ptr:
+		elem, err := convertValue(raw, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out = reflect.New(t.Elem())
+		out.Elem().Set(elem)
+
+	case t.Kind() == reflect.String:
+		out.SetString(fmt.Sprint(raw))
+
+	case t.Kind() == reflect.Bool:
+		if !isString {
+			return fail(nil)
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fail(err)
+		}
+		out.SetBool(b)
+
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		var n int64
+		switch {
+		case isString:
+			var err error
+			if n, err = strconv.ParseInt(strings.TrimSpace(s), 0, t.Bits()); err != nil {
+				return fail(err)
+			}
+		case v.CanInt():
+			n = v.Int()
+		case v.CanUint() && v.Uint() <= 1<<63-1:
+			n = int64(v.Uint())
+		case v.CanFloat() && v.Float() == float64(int64(v.Float())):
+			n = int64(v.Float())
+		default:
+			return fail(nil)
+		}
+		if out.OverflowInt(n) {
+			return fail(nil)
+		}
+		out.SetInt(n)
+
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		var n uint64
+		switch {
+		case isString:
+			var err error
+			if n, err = strconv.ParseUint(strings.TrimSpace(s), 0, t.Bits()); err != nil {
+				return fail(err)
+			}
+		case v.CanUint():
+			n = v.Uint()
+		case v.CanInt() && v.Int() >= 0:
+			n = uint64(v.Int())
+		case v.CanFloat() && v.Float() >= 0 && v.Float() == float64(uint64(v.Float())):
+			n = uint64(v.Float())
+		default:
+			return fail(nil)
+		}
+		if out.OverflowUint(n) {
+			return fail(nil)
+		}
+		out.SetUint(n)
+
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		var f float64
+		switch {
+		case isString:
+			var err error
+			if f, err = strconv.ParseFloat(strings.TrimSpace(s), t.Bits()); err != nil {
+				return fail(err)
+			}
+		case v.CanFloat():
+			f = v.Float()
+		case v.CanInt():
+			f = float64(v.Int())
+		case v.CanUint():
+			f = float64(v.Uint())
+		default:
+			return fail(nil)
+		}
+		out.SetFloat(f)
+
+	case t.Kind() == reflect.Slice:
+		items := v
+		if isString {
+			var parts []string
+			if strings.TrimSpace(s) != "" {
+				parts = strings.Split(s, ",")
+			}
+			items = reflect.ValueOf(parts)
+		} else if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fail(nil)
+		}
+
+		out = reflect.MakeSlice(t, items.Len(), items.Len())
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i).Interface()
+			if part, ok := item.(string); ok && isString {
+				item = strings.TrimSpace(part)
+			}
+			elem, err := convertValue(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("Item %d: %w", i, err)
+			}
+			out.Index(i).Set(elem)
+		}
+
+	default:
+		return fail(nil)
+	}
+	return out, nil
+}
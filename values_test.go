@@ -0,0 +1,226 @@
+package godi
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	TDBConfig struct {
+		Host string
+		Port int `godi:"value=port,default=5432"`
+	}
+
+	TValues struct {
+		DSN     string        `godi:"value=db.dsn"`
+		Timeout time.Duration `godi:"value=db.timeout,default=5s"`
+		Retries int           `godi:"value=db.retries"`
+		Ratio   float64       `godi:"value=ratio,optional"`
+		Debug   bool          `godi:"value=debug,optional"`
+		Tags    []string      `godi:"value=tags,default=a,b"`
+		DB      TDBConfig     `godi:"value=db"`
+		Backup  *TDBConfig    `godi:"value=backup,optional"`
+		Dep     I1            `godi:""`
+
+		initTimeout time.Duration
+	}
+)
+
+func (p *TValues) GodiInit() error {
+	p.initTimeout = p.Timeout
+	return nil
+}
+
+func (s *GoDiTestSuite) registerValues() {
+	RegisterInstanceImplementor((*I1)(nil), T1{s: "dep"})
+	RegisterTypeImplementor(TValues{}, TValues{}, false, nil)
+}
+
+func (s *GoDiTestSuite) TestValueInjection() {
+	s.registerValues()
+	AddValueSource(MapSource(map[string]interface{}{
+		"db": map[string]interface{}{
+			"dsn":     "postgres://localhost",
+			"retries": "3",
+			"host":    "db1",
+		},
+		"debug": true,
+	}))
+
+	r, err := ResolveAs[*TValues](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "postgres://localhost", r.DSN)
+	assert.Equal(s.T(), 5*time.Second, r.Timeout)
+	assert.Equal(s.T(), 5*time.Second, r.initTimeout, "values set before GodiInit")
+	assert.Equal(s.T(), 3, r.Retries)
+	assert.True(s.T(), r.Debug)
+	assert.Equal(s.T(), []string{"a", "b"}, r.Tags)
+	assert.Equal(s.T(), TDBConfig{Host: "db1", Port: 5432}, r.DB)
+	assert.Nil(s.T(), r.Backup)
+	assert.Equal(s.T(), "dep", r.Dep.F1())
+
+	// value fields aren't dependencies
+	assert.Nil(s.T(), Verify(false))
+}
+
+func (s *GoDiTestSuite) TestValueMissing() {
+	s.registerValues()
+	AddValueSource(MapSource(map[string]interface{}{"db.dsn": "x"}))
+
+	_, err := ResolveAs[*TValues](nil)
+	assert.NotNil(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "No value for 'db.retries'")
+}
+
+func (s *GoDiTestSuite) TestValueConversionError() {
+	s.registerValues()
+	AddValueSource(MapSource(map[string]interface{}{
+		"db.dsn":     "x",
+		"db.retries": 1,
+		"db.timeout": "soon",
+	}))
+
+	_, err := ResolveAs[*TValues](nil)
+	assert.NotNil(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "db.timeout")
+}
+
+func (s *GoDiTestSuite) TestValueSourcePrecedence() {
+	s.registerValues()
+	AddValueSource(MapSource(map[string]interface{}{"db.dsn": "root", "db.retries": 1}))
+	AddValueSource(MapSource(map[string]interface{}{"db.dsn": "later"}))
+
+	scope := CreateScope(false)
+	defer scope.Close()
+
+	r, _ := ResolveAs[*TValues](scope)
+	assert.Equal(s.T(), "later", r.DSN)
+
+	closer := scope.AddValueSource(Sources(
+		MapSource(map[string]interface{}{"db.dsn": "first"}),
+		MapSource(map[string]interface{}{"db.dsn": "second", "db.retries": 2}),
+	))
+	r, _ = ResolveAs[*TValues](scope)
+	assert.Equal(s.T(), "first", r.DSN)
+	assert.Equal(s.T(), 2, r.Retries)
+
+	closer.Close()
+	r, _ = ResolveAs[*TValues](scope)
+	assert.Equal(s.T(), "later", r.DSN)
+}
+
+type TPrivateValues struct {
+	DSN     string `godi:"value=db.dsn"`
+	retries int    `godi:"value=db.retries"`
+}
+
+func (p *TPrivateValues) F3() string { return p.DSN }
+
+func (s *GoDiTestSuite) TestValueUnexportedFromProvider() {
+	AddValueSource(MapSource(map[string]interface{}{"db.dsn": "x", "db.retries": 3}))
+	RegisterTypeImplementor(TPrivateValues{}, TPrivateValues{}, false, nil)
+	RegisterProvider((*I3)(nil), func() *TPrivateValues { return &TPrivateValues{} }, false, Named("provided"))
+
+	r, err := ResolveAs[*TPrivateValues](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 3, r.retries)
+
+	// godi doesn't own the unexported state of provider results
+	p, err := ResolveNamed((*I3)(nil), "provided")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "x", p.(*TPrivateValues).DSN)
+	assert.Equal(s.T(), 0, p.(*TPrivateValues).retries)
+}
+
+func (s *GoDiTestSuite) TestValuesForCachedFromRegistrationScope() {
+	AddValueSource(MapSource(map[string]interface{}{"db.dsn": "root", "db.retries": 1}))
+	RegisterTypeImplementor(TPrivateValues{}, TPrivateValues{}, true, nil)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	scope.AddValueSource(MapSource(map[string]interface{}{"db.dsn": "scope"}))
+
+	r, err := ResolveAs[*TPrivateValues](scope)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "root", r.DSN)
+}
+
+func (s *GoDiTestSuite) TestEnvSource() {
+	os.Setenv("GODI_TEST_DB_DSN", "env")
+	os.Setenv("GODI_TEST_DB_RETRIES", "0x10")
+	os.Setenv("GODI_TEST_TAGS", "x, y ,z")
+	defer os.Unsetenv("GODI_TEST_DB_DSN")
+	defer os.Unsetenv("GODI_TEST_DB_RETRIES")
+	defer os.Unsetenv("GODI_TEST_TAGS")
+
+	s.registerValues()
+	AddValueSource(EnvSource("GODI_TEST_"))
+
+	r, err := ResolveAs[*TValues](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "env", r.DSN)
+	assert.Equal(s.T(), 16, r.Retries)
+	assert.Equal(s.T(), []string{"x", "y", "z"}, r.Tags)
+}
+
+func (s *GoDiTestSuite) TestJSONSource() {
+	path := filepath.Join(s.T().TempDir(), "values.json")
+	os.WriteFile(path, []byte(`{
+		"db": {"DSN": "json", "retries": 7, "timeout": "1m"},
+		"ratio": 0.5,
+		"tags": ["p", "q"],
+		"backup": {"host": "db2", "port": 6543}
+	}`), 0o644)
+
+	s.registerValues()
+	source, err := JSONFileSource(path)
+	assert.Nil(s.T(), err)
+	AddValueSource(source)
+
+	r, err := ResolveAs[*TValues](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "json", r.DSN)
+	assert.Equal(s.T(), 7, r.Retries)
+	assert.Equal(s.T(), time.Minute, r.Timeout)
+	assert.Equal(s.T(), 0.5, r.Ratio)
+	assert.Equal(s.T(), []string{"p", "q"}, r.Tags)
+	assert.Equal(s.T(), &TDBConfig{Host: "db2", Port: 6543}, r.Backup)
+
+	_, err = JSONSource(strings.NewReader("[1]"))
+	assert.NotNil(s.T(), err)
+}
+
+func (s *GoDiTestSuite) TestFlagSource() {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("db.dsn", "unset", "")
+	fs.Int("db.retries", 9, "")
+	fs.Duration("db.timeout", time.Second, "")
+	assert.Nil(s.T(), fs.Parse([]string{"-db.retries=4", "-db.timeout=2s"}))
+
+	s.registerValues()
+	AddValueSource(MapSource(map[string]interface{}{"db.dsn": "map"}))
+	AddValueSource(FlagSource(fs))
+
+	r, err := ResolveAs[*TValues](nil)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "map", r.DSN, "unset flags fall through")
+	assert.Equal(s.T(), 4, r.Retries)
+	assert.Equal(s.T(), 2*time.Second, r.Timeout)
+}
+
+func (s *GoDiTestSuite) TestValueTag() {
+	type bad struct {
+		X int `godi:"default=1"`
+	}
+	RegisterTypeImplementor(bad{}, bad{}, false, nil)
+	_, err := Resolve(bad{})
+	assert.NotNil(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "default requires value")
+}
@@ -0,0 +1,141 @@
+package godi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --------
+//
+// Verification checks every registration visible from a scope up front, so
+// misconfigurations are found at startup rather than on the first Resolve
+// that happens to need them.
+//
+// --------
+
+// VerificationProblem describes one registration that failed verification.
+type VerificationProblem struct {
+	Scope       string
+	Target      string
+	Name        string
+	Implementor string
+	Err         error
+}
+
+func (p *VerificationProblem) Error() string {
+	target := p.Target
+	if p.Name != "" {
+		target += "[" + p.Name + "]"
+	}
+	return fmt.Sprintf("%s: %s -> %s: %v", p.Scope, target, p.Implementor, p.Err)
+}
+
+func (p *VerificationProblem) Unwrap() error {
+	return p.Err
+}
+
+// VerificationError is returned by Verify, and lists every problem found.
+type VerificationError struct {
+	Problems []*VerificationProblem
+}
+
+func (p *VerificationError) Error() string {
+	lines := make([]string, 0, len(p.Problems)+1)
+	lines = append(lines, fmt.Sprintf("%d registration problem(s) found:", len(p.Problems)))
+	for _, problem := range p.Problems {
+		lines = append(lines, "  "+problem.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap allows errors.Is and errors.As to match any of the problems.
+func (p *VerificationError) Unwrap() []error {
+	errs := make([]error, len(p.Problems))
+	for i, problem := range p.Problems {
+		errs[i] = problem
+	}
+	return errs
+}
+
+// Verify checks every registration in this scope, its parents and the
+// override scope:
+//
+// - name-based targets and implementors have been registered with RegisterType
+// - name-based implementors implement their targets
+// - provider parameters and injected fields have registrations, unless optional
+// - if construct is true, each registration can actually be resolved
+//
+// Registrations whose profiles or conditions don't hold are skipped.
+//
+// Constructing a cached registration creates and keeps its instance, as if it
+// had been resolved.  Returns a *VerificationError listing every problem, or
+// nil.
+func (p *registrationContext) Verify(construct bool) error {
+	var problems []*VerificationProblem
+
+	for _, ctx := range p.lookupChain() {
+		for _, reg := range ctx.allRegistrations() {
+			if !reg.active() {
+				continue
+			}
+			for _, err := range p.verifyRegistration(reg, construct) {
+				problems = append(problems, &VerificationProblem{
+					Scope:       ctx.name,
+					Target:      reg.targetType.typeName,
+					Name:        reg.name,
+					Implementor: reg.implType.typeName,
+					Err:         err,
+				})
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &VerificationError{Problems: problems}
+	}
+	return nil
+}
+
+func (p *registrationContext) verifyRegistration(reg *typeRegistration, construct bool) []error {
+	var errs []error
+
+	target, targetErr := reg.targetType.Type()
+	if targetErr != nil {
+		errs = append(errs, targetErr)
+	}
+
+	impl, implErr := reg.implType.Type()
+	if implErr != nil {
+		errs = append(errs, implErr)
+	}
+
+	if targetErr == nil && implErr == nil {
+		if err := reg.ensureImplementor(impl, target); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	deps, err := reg.dependencies()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, dep := range deps {
+		if dep.optional {
+			continue
+		}
+		if found, searched := p.lookupRegistration(dep.typeName(), dep.name); found == nil {
+			errs = append(errs, fmt.Errorf("missing dependency: %w", &NotFoundError{TypeName: dep.typeName(), Name: dep.name, Scopes: searched}))
+		}
+	}
+
+	// only try to construct registrations that otherwise look good, so the
+	// same problem isn't reported twice.
+	if construct && len(errs) == 0 {
+		state := newResolveState()
+		if _, err := p.resolveRegistration(state, reg, nil); err != nil {
+			errs = append(errs, err)
+		}
+		state.leave()
+	}
+	return errs
+}
@@ -0,0 +1,63 @@
+package godi
+
+import (
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *GoDiTestSuite) TestVerify() {
+	RegisterType((*I1)(nil))
+	RegisterType(T2{})
+	RegisterByName("godi.I1", "godi.T2", true)
+	RegisterTypeImplementor((*I3)(nil), TInjected{}, false, nil)
+	RegisterTypeImplementor(T3{}, T3{}, false, nil)
+
+	assert.Nil(s.T(), Verify(true))
+}
+
+func (s *GoDiTestSuite) TestVerifyProblems() {
+	RegisterType((*I1)(nil))
+	RegisterType(T1{})
+	RegisterType((*I2)(nil))
+
+	// typo'd implementor
+	RegisterByName("godi.I1", "godi.T9", false)
+	// doesn't implement the target
+	RegisterByName("godi.I2", "godi.T1", false)
+	// missing field dependencies (I1 is name-based and broken, T3 is missing)
+	RegisterTypeImplementor((*I3)(nil), TInjected{}, false, nil)
+
+	scope := CreateScope(false)
+	defer scope.Close()
+	// missing provider parameter, and missing field on the result
+	scope.RegisterProvider((*ICycleB)(nil), func(a ICycleA) *TCycleB { return nil }, false)
+
+	err := scope.Verify(false)
+
+	var verifyErr *VerificationError
+	assert.True(s.T(), errors.As(err, &verifyErr))
+	assert.Equal(s.T(), 5, len(verifyErr.Problems))
+
+	var unknown *UnknownTypeError
+	assert.True(s.T(), errors.As(err, &unknown))
+	assert.Equal(s.T(), "godi.T9", unknown.TypeName)
+
+	var notImpl *NotImplementedError
+	assert.True(s.T(), errors.As(err, &notImpl))
+
+	assert.True(s.T(), errors.Is(err, ErrNotFound))
+	assert.Equal(s.T(), scope.(*registrationContext).name, verifyErr.Problems[0].Scope)
+	assert.Equal(s.T(), scope.(*registrationContext).name, verifyErr.Problems[1].Scope)
+	assert.Equal(s.T(), "root", verifyErr.Problems[2].Scope)
+}
+
+func (s *GoDiTestSuite) TestVerifyConstruct() {
+	RegisterTypeImplementor((*I1)(nil), TFail{}, false, nil)
+
+	assert.Nil(s.T(), Verify(false))
+
+	err := Verify(true)
+	var initErr *InitError
+	assert.True(s.T(), errors.As(err, &initErr))
+}